@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// stage limits how many steps of one kind run at once and, for API stages,
+// how many requests per minute they send
+type stage struct {
+	slots   chan struct{}
+	limiter *rate.Limiter // Nil for local stages
+}
+
+// batchStages are the stages steps are assigned to by limitStep
+type batchStages struct {
+	local         *stage
+	transcription *stage
+	llm           *stage
+	embedding     *stage // Embedding requests have their own rate limits
+}
+
+func newStage(workers int, perMinute float64) *stage {
+	s := &stage{slots: make(chan struct{}, workers)}
+	if perMinute > 0 {
+		s.limiter = rate.NewLimiter(rate.Limit(perMinute/60), workers)
+	}
+	return s
+}
+
+// limitedStep runs a step within the limits of its stage
+type limitedStep struct {
+	Step
+	stage    *stage
+	requests int // API requests the step sends
+}
+
+func (s *limitedStep) Execute(ctx context.Context, state *State) error {
+	select {
+	case s.stage.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.stage.slots }()
+
+	if s.stage.limiter != nil {
+		if err := s.stage.limiter.WaitN(ctx, min(s.requests, s.stage.limiter.Burst())); err != nil {
+			return err
+		}
+	}
+	return s.Step.Execute(ctx, state)
+}
+
+// batchResult is the outcome of processing one file
+type batchResult struct {
+	File     string
+	Folder   string
+	Cost     string
+	Duration time.Duration
+	Err      error
+}
+
+// runBatch processes many audio files in parallel. Transcription, LLM and
+// embedding steps have separate concurrency limits and rate limiters, so a
+// large backlog does not trip the API rate limits.
+func runBatch(ctx context.Context, opts pipelineOptions, args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	transcribeWorkers := fs.Int("transcribe-workers", 4, "Number of files transcribed at once")
+	llmWorkers := fs.Int("llm-workers", 4, "Number of LLM requests sent at once")
+	transcribeRate := fs.Float64("transcribe-rpm", 50, "Maximum transcription requests per minute (0 disables the limit)")
+	llmRate := fs.Float64("llm-rpm", 500, "Maximum LLM requests per minute (0 disables the limit)")
+	embedWorkers := fs.Int("embed-workers", 4, "Number of embedding requests sent at once")
+	embedRate := fs.Float64("embed-rpm", 500, "Maximum embedding requests per minute (0 disables the limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: batch [-transcribe-workers n] [-llm-workers n] [-embed-workers n] [-transcribe-rpm n] [-llm-rpm n] [-embed-rpm n] <audio file>...")
+	}
+	if *transcribeWorkers < 1 || *llmWorkers < 1 || *embedWorkers < 1 {
+		return fmt.Errorf("worker counts must be at least 1")
+	}
+	files := fs.Args()
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+	}
+
+	stages := batchStages{
+		local:         newStage(runtime.NumCPU(), 0),
+		transcription: newStage(*transcribeWorkers, *transcribeRate),
+		llm:           newStage(*llmWorkers, *llmRate),
+		embedding:     newStage(*embedWorkers, *embedRate),
+	}
+
+	opts.Quiet = true
+	results := make([]batchResult, len(files))
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Steps are logged with the recording, which identifies the file
+			log := slog.With("file", filepath.Base(file), "progress", fmt.Sprintf("%d/%d", i+1, len(files)))
+			fileOpts := opts
+			fileOpts.Input = file
+			start := time.Now()
+			state, pipeline, err := newRun(fileOpts)
+			if err == nil {
+				log.Info("Processing file", "recording", state.Timestamp)
+				for j, step := range pipeline {
+					pipeline[j] = limitStep(step, stages)
+				}
+				err = runPipeline(ctx, pipeline, state, nil)
+			}
+
+			result := batchResult{File: file, Duration: time.Since(start), Err: err}
+			if state != nil {
+				result.Folder = state.OutFolder
+				result.Cost = fmt.Sprintf("$%.4f", state.Usage.Summary().Cost)
+			}
+			results[i] = result
+
+			if err != nil {
+				log.Error("Processing file failed", "err", err)
+			} else {
+				log.Info("Processed file", "folder", result.Folder)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tSTATUS\tDURATION\tCOST\tRESULT")
+	failed := 0
+	for _, r := range results {
+		status, detail := "ok", r.Folder
+		if r.Err != nil {
+			status, detail = "failed", r.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.File, status, r.Duration.Round(time.Second), r.Cost, detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(files))
+	}
+	return nil
+}
+
+// limitStep assigns a step to the stage of the API it calls
+func limitStep(step Step, stages batchStages) Step {
+	switch s := step.(type) {
+	case *TranscribeStep:
+		return &limitedStep{Step: s, stage: stages.transcription, requests: 1}
+	case *TranslateStep:
+		return &limitedStep{Step: s, stage: stages.llm, requests: len(s.Languages)}
+	case *DiarizeStep, *EditStep, *ActionsStep, *TagsStep, *HeadlineStep:
+		return &limitedStep{Step: s, stage: stages.llm, requests: 1}
+	case *EmbedStep:
+		return &limitedStep{Step: s, stage: stages.embedding, requests: 1}
+	default:
+		return &limitedStep{Step: s, stage: stages.local}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// countingStep records how many of its executions overlap
+type countingStep struct {
+	running, maxRunning, calls atomic.Int32
+	hold                       time.Duration
+}
+
+func (s *countingStep) Name() string      { return "counting" }
+func (s *countingStep) Requires() []Field { return nil }
+func (s *countingStep) Produces() []Field { return nil }
+
+func (s *countingStep) Execute(ctx context.Context, state *State) error {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		highest := s.maxRunning.Load()
+		if n <= highest || s.maxRunning.CompareAndSwap(highest, n) {
+			break
+		}
+	}
+	s.calls.Add(1)
+	time.Sleep(s.hold)
+	return nil
+}
+
+func TestNewStage(t *testing.T) {
+	local := newStage(3, 0)
+	if cap(local.slots) != 3 || local.limiter != nil {
+		t.Errorf("Expected 3 slots without a rate limit, got %d slots and %v", cap(local.slots), local.limiter)
+	}
+
+	api := newStage(4, 120)
+	if cap(api.slots) != 4 || api.limiter.Limit() != rate.Limit(2) || api.limiter.Burst() != 4 {
+		t.Errorf("Expected 4 slots at 2 requests per second with a burst of 4, got %d slots, %v and %d",
+			cap(api.slots), api.limiter.Limit(), api.limiter.Burst())
+	}
+}
+
+func TestLimitStep(t *testing.T) {
+	stages := batchStages{
+		local:         newStage(1, 0),
+		transcription: newStage(1, 60),
+		llm:           newStage(1, 60),
+		embedding:     newStage(1, 60),
+	}
+
+	for _, tc := range []struct {
+		step     Step
+		stage    *stage
+		requests int
+	}{
+		{step: &ImportStep{}, stage: stages.local},
+		{step: &EncodeStep{}, stage: stages.local},
+		{step: &TranscribeStep{}, stage: stages.transcription, requests: 1},
+		{step: &EditStep{}, stage: stages.llm, requests: 1},
+		{step: &HeadlineStep{}, stage: stages.llm, requests: 1},
+		{step: &TranslateStep{Languages: []string{"de", "fr", "es"}}, stage: stages.llm, requests: 3},
+		{step: &EmbedStep{}, stage: stages.embedding, requests: 1},
+		{step: &SaveStep{}, stage: stages.local},
+	} {
+		limited := limitStep(tc.step, stages).(*limitedStep)
+		if limited.Step != tc.step || limited.stage != tc.stage || limited.requests != tc.requests {
+			t.Errorf("Unexpected limits for %s: %+v", tc.step.Name(), limited)
+		}
+	}
+}
+
+func TestLimitedStepSlots(t *testing.T) {
+	step := &countingStep{hold: 20 * time.Millisecond}
+	limited := &limitedStep{Step: step, stage: newStage(2, 0)}
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limited.Execute(context.Background(), &State{}); err != nil {
+				t.Errorf("Execute failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if step.calls.Load() != 6 || step.maxRunning.Load() != 2 {
+		t.Errorf("Expected 6 calls with at most 2 at once, got %d calls with %d at once", step.calls.Load(), step.maxRunning.Load())
+	}
+
+	// Waiting for a slot ends with the context
+	stage := newStage(1, 0)
+	stage.slots <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := (&limitedStep{Step: step, stage: stage}).Execute(ctx, &State{})
+	if !errors.Is(err, context.DeadlineExceeded) || step.calls.Load() != 6 {
+		t.Errorf("Expected the step not to run without a slot, got %v", err)
+	}
+}
+
+func TestLimitedStepWaitN(t *testing.T) {
+	step := &countingStep{}
+	// One request per second with a burst of 2
+	stage := newStage(2, 60)
+
+	// Requests beyond the burst are capped, so the step can run at all
+	start := time.Now()
+	if err := (&limitedStep{Step: step, stage: stage, requests: 5}).Execute(context.Background(), &State{}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Expected the burst to be available at once, took %s", time.Since(start))
+	}
+
+	// The burst is used up, so the next request must wait about a second
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := (&limitedStep{Step: step, stage: stage, requests: 1}).Execute(ctx, &State{}); err == nil {
+		t.Error("Expected the rate limit to delay the step beyond the deadline")
+	}
+	if step.calls.Load() != 1 {
+		t.Errorf("Expected the limited step to run once, got %d", step.calls.Load())
+	}
+
+	// Local stages are not rate limited
+	local := &limitedStep{Step: step, stage: newStage(1, 0), requests: 100}
+	if err := local.Execute(context.Background(), &State{}); err != nil || step.calls.Load() != 2 {
+		t.Errorf("Expected the local step to run, got %v", err)
+	}
+}
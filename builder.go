@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/r4h4/article-helper/config"
+	"github.com/r4h4/article-helper/delivery"
+	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/embed"
+	"github.com/r4h4/article-helper/encoder"
+	"github.com/r4h4/article-helper/export"
+	"github.com/r4h4/article-helper/glossary"
+	"github.com/r4h4/article-helper/internal/httplog"
+	"github.com/r4h4/article-helper/usage"
+	"github.com/r4h4/article-helper/whisper"
+)
+
+// pipelineOptions select and configure the steps of a pipeline run
+type pipelineOptions struct {
+	APIKey  string
+	BaseURL string // OpenAI compatible API, e.g. https://api.openai.com/v1
+
+	// Input is an audio file to process instead of recording
+	Input           string
+	OutputFile      string
+	Device          string
+	AutoStopSilence time.Duration
+	Trim            bool
+	Format          encoder.Format
+
+	Language     string
+	Glossary     *glossary.Glossary
+	Diarize      bool
+	SpeakerNames map[string]string
+	TranslateTo  []string
+	Actions      bool
+	Tags         bool
+	Taxonomy     []string
+	Embed        *embed.Client // Nil disables embedding
+	Exports      []config.Export
+	Deliveries   []config.Delivery
+	Plugins      []config.Plugin
+	Headlines    int      // Headline candidates per style
+	Skip         []string // Names of steps not to run, e.g. "headline" and "rename"
+	Quiet        bool     // Do not print the results
+	Review       bool     // Let the user review and revise the results before saving
+	LogHTTP      bool     // Log the API requests to http.log in the recording folder
+	Prices       usage.Prices
+}
+
+// Transcriber transcribes audio files, e.g. *whisper.Client
+type Transcriber interface {
+	Transcribe(ctx context.Context, filePath string, opts whisper.Options) (*whisper.Transcription, error)
+}
+
+// Editor processes transcripts with a language model, e.g. *editor.AIEditor
+type Editor interface {
+	EditAndSummarize(ctx context.Context, transcript string, opts editor.EditOptions) (*editor.EditorResponse, error)
+	CreateHeadline(ctx context.Context, summary string, n int) (*editor.HeadlineResponse, error)
+	AttributeSpeakers(ctx context.Context, transcript string) (*editor.SpeakerResponse, error)
+	ExtractActions(ctx context.Context, transcript string) (*editor.ActionsResponse, error)
+	ExtractTags(ctx context.Context, transcript string, taxonomy []string) (*editor.TagsResponse, error)
+	Translate(ctx context.Context, edited *editor.EditorResponse, language string) (*editor.EditorResponse, error)
+}
+
+// Clients are the API clients and user interfaces the steps of a pipeline call
+type Clients struct {
+	Transcriber Transcriber
+	Editor      Editor
+	Embedder    *embed.Client // Nil disables embedding
+	Reviewer    Reviewer
+	Exporters   []export.Exporter
+	Deliverers  []delivery.Deliverer
+}
+
+// newClients returns the API clients of a run, recording their usage in
+// tracker. The requests are sent through rt, if not nil.
+func newClients(opts pipelineOptions, tracker *usage.Tracker, rt http.RoundTripper) (Clients, error) {
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	ed := editor.NewAIEditor(opts.APIKey, baseURL+"/chat/completions")
+	ed.TrackUsage(tracker)
+	ed.UseTransport(rt)
+	clients := Clients{
+		Transcriber: whisper.NewClient(whisper.Config{
+			APIEndpoint: baseURL + "/audio/transcriptions",
+			APIKey:      opts.APIKey,
+			Transport:   rt,
+		}),
+		Editor:   ed,
+		Reviewer: terminalReviewer{},
+	}
+	if opts.Embed != nil {
+		clients.Embedder = opts.Embed.WithTracker(tracker)
+		if rt != nil {
+			clients.Embedder = clients.Embedder.WithTransport(rt)
+		}
+	}
+	for _, e := range opts.Exports {
+		exporter, err := newExporter(e, rt)
+		if err != nil {
+			return Clients{}, err
+		}
+		clients.Exporters = append(clients.Exporters, exporter)
+	}
+	for _, d := range opts.Deliveries {
+		deliverer, err := newDeliverer(d)
+		if err != nil {
+			return Clients{}, err
+		}
+		clients.Deliverers = append(clients.Deliverers, deliverer)
+	}
+	return clients, nil
+}
+
+// newRun reserves a recording folder and builds the pipeline filling it
+func newRun(opts pipelineOptions) (*State, []Step, error) {
+	state, err := newState(usage.NewTracker(opts.Prices))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rt http.RoundTripper
+	if opts.LogHTTP {
+		logger := slog.New(slog.NewJSONHandler(&folderLog{state: state, name: httpLogFile}, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		rt = &httplog.Transport{Logger: logger}
+	}
+	var pipeline []Step
+	clients, err := newClients(opts, state.Usage, rt)
+	if err == nil {
+		pipeline, err = newPipeline(opts, clients)
+	}
+	if err != nil {
+		os.Remove(state.OutFolder)
+		return nil, nil, err
+	}
+	return state, pipeline, nil
+}
+
+// newPipeline returns the steps for a recording or, with opts.Input, an audio
+// file, with the configured plugins. Steps named in opts.Skip are left out.
+func newPipeline(opts pipelineOptions, clients Clients) ([]Step, error) {
+	var pipeline []Step
+	if opts.Input == "" {
+		pipeline = append(pipeline, &RecordStep{OutputFile: &opts.OutputFile, Device: opts.Device, AutoStopSilence: opts.AutoStopSilence})
+	} else {
+		pipeline = append(pipeline, &ImportStep{Input: opts.Input})
+	}
+	// Other formats are uploaded as they are
+	if opts.Input == "" || strings.EqualFold(filepath.Ext(opts.Input), ".wav") {
+		if opts.Trim {
+			pipeline = append(pipeline, &TrimStep{})
+		}
+		pipeline = append(pipeline, &EncodeStep{Format: opts.Format})
+	}
+	pipeline = append(pipeline,
+		&TranscribeStep{Client: clients.Transcriber, Language: opts.Language, Glossary: opts.Glossary},
+	)
+	if opts.Glossary != nil {
+		pipeline = append(pipeline, &GlossaryStep{Glossary: opts.Glossary})
+	}
+	if opts.Diarize {
+		pipeline = append(pipeline, &DiarizeStep{Editor: clients.Editor, SpeakerNames: opts.SpeakerNames})
+	}
+	pipeline = append(pipeline,
+		&EditStep{Editor: clients.Editor, Glossary: opts.Glossary},
+		&HeadlineStep{Editor: clients.Editor, Candidates: opts.Headlines},
+	)
+	// Translations are of the reviewed text
+	if opts.Review {
+		pipeline = append(pipeline, &ReviewStep{Editor: clients.Editor, Reviewer: clients.Reviewer, Glossary: opts.Glossary, Candidates: opts.Headlines})
+	}
+	if len(opts.TranslateTo) > 0 {
+		pipeline = append(pipeline, &TranslateStep{Editor: clients.Editor, Languages: opts.TranslateTo})
+	}
+	if opts.Actions {
+		pipeline = append(pipeline, &ActionsStep{Editor: clients.Editor})
+	}
+	if opts.Tags {
+		pipeline = append(pipeline, &TagsStep{Editor: clients.Editor, Taxonomy: opts.Taxonomy})
+	}
+	// The slug names the folder, so it is renamed before everything is saved into it
+	pipeline = append(pipeline,
+		&RenameStep{},
+		&SaveStep{Quiet: opts.Quiet},
+	)
+	if clients.Embedder != nil {
+		pipeline = append(pipeline, &EmbedStep{Client: clients.Embedder})
+	}
+	if len(clients.Exporters) > 0 {
+		pipeline = append(pipeline, &ExportStep{Exporters: clients.Exporters})
+	}
+	if len(clients.Deliverers) > 0 {
+		pipeline = append(pipeline, &DeliverStep{Deliverers: clients.Deliverers})
+	}
+
+	for _, p := range opts.Plugins {
+		plugin, err := newPluginStep(p)
+		if err != nil {
+			return nil, err
+		}
+		if pipeline, err = insertPlugin(pipeline, plugin); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range opts.Skip {
+		if !slices.ContainsFunc(pipeline, func(step Step) bool { return step.Name() == name }) {
+			return nil, fmt.Errorf("cannot skip %s: no such step in the pipeline", name)
+		}
+	}
+	pipeline = slices.DeleteFunc(pipeline, func(step Step) bool { return slices.Contains(opts.Skip, step.Name()) })
+	// Without a headline there is no slug to name the folder after, so it keeps its name
+	if !producedBefore(pipeline, FieldSlug, "rename") {
+		pipeline = slices.DeleteFunc(pipeline, func(step Step) bool { return step.Name() == "rename" })
+	}
+
+	if err := validatePipeline(pipeline); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// validatePipeline checks that every step runs after the steps producing its
+// inputs, and before any step changing an input of an earlier step, unless it
+// also produces everything that step produced
+func validatePipeline(pipeline []Step) error {
+	produced := make(map[Field]bool)
+	consumers := make(map[Field][]Step)
+	for _, step := range pipeline {
+		for _, field := range step.Requires() {
+			if !produced[field] {
+				return fmt.Errorf("%s requires %s, which no earlier step produces", step.Name(), field)
+			}
+		}
+		for _, field := range step.Produces() {
+			for _, consumer := range consumers[field] {
+				if !containsAll(step.Produces(), consumer.Produces()) {
+					return fmt.Errorf("%s changes %s after %s used it", step.Name(), field, consumer.Name())
+				}
+			}
+		}
+		for _, field := range step.Requires() {
+			consumers[field] = append(consumers[field], step)
+		}
+		for _, field := range step.Produces() {
+			produced[field] = true
+		}
+	}
+	return nil
+}
+
+// producedBefore reports whether a step before the one named name produces field
+func producedBefore(pipeline []Step, field Field, name string) bool {
+	for _, step := range pipeline {
+		if step.Name() == name {
+			return false
+		}
+		if slices.Contains(step.Produces(), field) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(fields, subset []Field) bool {
+	for _, field := range subset {
+		if !slices.Contains(fields, field) {
+			return false
+		}
+	}
+	return true
+}
+
+// runPipeline executes the steps in order, calling progress before each step
+// if not nil. When a step fails or ctx is cancelled, the state is saved to the
+// recording folder.
+func runPipeline(ctx context.Context, pipeline []Step, state *State, progress func(Step)) error {
+	if err := validatePipeline(pipeline); err != nil {
+		return err
+	}
+
+	log := slog.With("recording", state.Timestamp)
+	for i, step := range pipeline {
+		// Steps such as recording stop without an error when cancelled
+		if err := ctx.Err(); err != nil {
+			return saveCheckpoint(state, pipeline[:i], err)
+		}
+		if progress != nil {
+			progress(step)
+		}
+		log.Debug("Running step", "step", step.Name())
+		start := time.Now()
+		if err := step.Execute(ctx, state); err != nil {
+			return saveCheckpoint(state, pipeline[:i], fmt.Errorf("%s: %w", step.Name(), err))
+		}
+		log.Info("Step done", "step", step.Name(), "duration", time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// checkpointFile holds the state of a run that did not finish
+const checkpointFile = "state.json"
+
+// checkpoint describes how far a run got
+type checkpoint struct {
+	Completed []string `json:"completed"`
+	Error     string   `json:"error"`
+	State     *State   `json:"state"`
+}
+
+// saveCheckpoint saves the state after the completed steps and returns cause
+func saveCheckpoint(state *State, completed []Step, cause error) error {
+	if state.OutFolder == "" {
+		return cause
+	}
+
+	c := checkpoint{Error: cause.Error(), State: state}
+	for _, step := range completed {
+		c.Completed = append(c.Completed, step.Name())
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(state.OutFolder, checkpointFile), data, 0644)
+	}
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("saving state: %w", err))
+	}
+	return cause
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/r4h4/article-helper/config"
+	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/whisper"
+)
+
+type fakeTranscriber struct{}
+
+func (fakeTranscriber) Transcribe(ctx context.Context, filePath string, opts whisper.Options) (*whisper.Transcription, error) {
+	return &whisper.Transcription{Text: "raw " + filepath.Base(filePath), Language: "english", Duration: 60}, nil
+}
+
+type fakeEditor struct{}
+
+func (fakeEditor) EditAndSummarize(ctx context.Context, transcript string, opts editor.EditOptions) (*editor.EditorResponse, error) {
+	return &editor.EditorResponse{CleanedTranscription: "cleaned " + transcript, Summary: "summary"}, nil
+}
+
+func (fakeEditor) CreateHeadline(ctx context.Context, summary string, n int) (*editor.HeadlineResponse, error) {
+	return &editor.HeadlineResponse{Headlines: editor.Headlines{{Style: editor.HeadlineTitle, Text: "Fake Headline"}}, Slug: "fake-headline"}, nil
+}
+
+func (fakeEditor) AttributeSpeakers(ctx context.Context, transcript string) (*editor.SpeakerResponse, error) {
+	return &editor.SpeakerResponse{}, nil
+}
+
+func (fakeEditor) ExtractActions(ctx context.Context, transcript string) (*editor.ActionsResponse, error) {
+	return &editor.ActionsResponse{}, nil
+}
+
+func (fakeEditor) ExtractTags(ctx context.Context, transcript string, taxonomy []string) (*editor.TagsResponse, error) {
+	return &editor.TagsResponse{Tags: []string{"fake"}}, nil
+}
+
+func (fakeEditor) Translate(ctx context.Context, edited *editor.EditorResponse, language string) (*editor.EditorResponse, error) {
+	return &editor.EditorResponse{CleanedTranscription: language, Summary: language}, nil
+}
+
+var fakeClients = Clients{Transcriber: fakeTranscriber{}, Editor: fakeEditor{}}
+
+func stepNames(pipeline []Step) []string {
+	names := make([]string, len(pipeline))
+	for i, step := range pipeline {
+		names[i] = step.Name()
+	}
+	return names
+}
+
+func TestNewPipeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     pipelineOptions
+		expected []string
+		err      string
+	}{
+		{
+			name:     "Record",
+			opts:     pipelineOptions{Trim: true, Tags: true},
+			expected: []string{"record", "trim", "encode", "transcribe", "edit", "headline", "tags", "rename", "save"},
+		},
+		{
+			name:     "Import",
+			opts:     pipelineOptions{Input: "memo.mp3", TranslateTo: []string{"french"}},
+			expected: []string{"import", "transcribe", "edit", "headline", "translate", "rename", "save"},
+		},
+		{
+			name:     "Review",
+			opts:     pipelineOptions{Input: "memo.mp3", Review: true, TranslateTo: []string{"french"}},
+			expected: []string{"import", "transcribe", "edit", "headline", "review", "translate", "rename", "save"},
+		},
+		{
+			name:     "SkipHeadline",
+			opts:     pipelineOptions{Input: "memo.mp3", Skip: []string{"headline", "rename"}},
+			expected: []string{"import", "transcribe", "edit", "save"},
+		},
+		{
+			name:     "SkipRename",
+			opts:     pipelineOptions{Input: "memo.mp3", Skip: []string{"rename"}},
+			expected: []string{"import", "transcribe", "edit", "headline", "save"},
+		},
+		{
+			name:     "MissingInput",
+			opts:     pipelineOptions{Input: "memo.mp3", Skip: []string{"headline"}},
+			expected: []string{"import", "transcribe", "edit", "save"},
+		},
+		{
+			name: "UnknownStep",
+			opts: pipelineOptions{Input: "memo.mp3", Skip: []string{"trim"}},
+			err:  "cannot skip trim: no such step in the pipeline",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := newPipeline(tt.opts, fakeClients)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if names := stepNames(pipeline); !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Expected steps %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestValidatePipeline(t *testing.T) {
+	err := validatePipeline([]Step{&ImportStep{}, &TranscribeStep{}, &EditStep{}, &GlossaryStep{}})
+	if err == nil || err.Error() != "glossary changes transcription after edit used it" {
+		t.Errorf("Expected an ordering error, got %v", err)
+	}
+
+	// Choosing a headline in the review changes the slug the folder is named after
+	err = validatePipeline([]Step{&ImportStep{}, &TranscribeStep{}, &EditStep{}, &HeadlineStep{}, &RenameStep{}, &ReviewStep{}})
+	if err == nil || err.Error() != "review changes slug after rename used it" {
+		t.Errorf("Expected an ordering error, got %v", err)
+	}
+
+	err = runPipeline(context.Background(), []Step{&ImportStep{}, &GlossaryStep{}, &TranscribeStep{}}, &State{}, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "glossary requires transcription") {
+		t.Errorf("Expected the runner to reject the pipeline, got %v", err)
+	}
+}
+
+func TestPipelineClients(t *testing.T) {
+	dir := chdirTemp(t)
+	input := filepath.Join(dir, "memo.mp3")
+	if err := os.WriteFile(input, []byte("ID3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline, err := newPipeline(pipelineOptions{Input: input, Tags: true, Skip: []string{"rename"}, Quiet: true}, fakeClients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := newState(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runPipeline(context.Background(), pipeline, state, nil); err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+
+	ts := state.Timestamp
+	if expected := "cleaned raw recording_" + ts + ".mp3"; state.CleanedTranscription != expected {
+		t.Errorf("Expected %q, got %q", expected, state.CleanedTranscription)
+	}
+	if state.Headline != "Fake Headline" || state.OutFolder != filepath.Join(recordingsDir, ts) {
+		t.Errorf("Expected the headline without renaming %s, got %q", state.OutFolder, state.Headline)
+	}
+	if _, err := os.Stat(filepath.Join(state.OutFolder, "summary_"+ts+".txt")); err != nil {
+		t.Errorf("Expected the summary to be saved: %v", err)
+	}
+}
+
+func TestPipelineExportsAndDeliveries(t *testing.T) {
+	dir := chdirTemp(t)
+	input := filepath.Join(dir, "memo.mp3")
+	if err := os.WriteFile(input, []byte("ID3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vault := filepath.Join(dir, "vault")
+	if err := os.Mkdir(vault, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var chat, hooks []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&payload)
+		if r.URL.Path == "/chat" {
+			chat = append(chat, payload.Text)
+		} else {
+			hooks = append(hooks, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	opts := pipelineOptions{
+		Input: input,
+		Quiet: true,
+		Exports: []config.Export{
+			{Type: "obsidian", Vault: vault},
+			{Type: "webhook", URL: server.URL + "/hook/secret", Headers: map[string]string{"X-Webhook-Secret": "secret"}},
+		},
+		Deliveries: []config.Delivery{{Type: "slack", URL: server.URL + "/chat"}},
+	}
+	// Stands in for the transport logging requests with -log-http
+	logged := &recordingTransport{}
+	clients, err := newClients(opts, nil, logged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients.Transcriber, clients.Editor = fakeTranscriber{}, fakeEditor{}
+	pipeline, err := newPipeline(opts, clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := stepNames(pipeline); !slices.Equal(names[len(names)-2:], []string{"export", "deliver"}) {
+		t.Fatalf("Expected the results to be exported and delivered last, got %v", names)
+	}
+	state, err := newState(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runPipeline(context.Background(), pipeline, state, nil); err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	notes, _ := filepath.Glob(filepath.Join(vault, "* Fake Headline.md"))
+	if len(notes) != 1 {
+		t.Errorf("Expected a note in the vault, got %v", notes)
+	}
+	if len(chat) != 1 || !strings.HasPrefix(chat[0], "Fake Headline") {
+		t.Errorf("Expected the summary in the chat, got %q", chat)
+	}
+	if len(hooks) != 1 {
+		t.Errorf("Expected the results to be posted to the webhook, got %v", hooks)
+	}
+	// Webhooks may carry secrets in their URL and headers
+	if len(logged.urls) != 0 {
+		t.Errorf("Expected webhook requests not to be logged, got %v", logged.urls)
+	}
+
+	t.Setenv("NOTION_TOKEN", "")
+	for _, e := range []config.Export{{Type: "evernote"}, {Type: "notion", PageID: "page"}, {Type: "webhook"}} {
+		if _, err := newClients(pipelineOptions{Exports: []config.Export{e}}, nil, nil); err == nil {
+			t.Errorf("Expected an error for export %+v", e)
+		}
+	}
+	for _, d := range []config.Delivery{{Type: "fax"}, {Type: "email", SMTPServer: "localhost:25", From: "notes@example.com"}, {Type: "teams"}} {
+		if _, err := newClients(pipelineOptions{Deliveries: []config.Delivery{d}}, nil, nil); err == nil {
+			t.Errorf("Expected an error for delivery %+v", d)
+		}
+	}
+}
+
+// recordingTransport records the URLs of the requests it sends
+type recordingTransport struct {
+	urls []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.urls = append(rt.urls, req.URL.String())
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/r4h4/article-helper/config"
+	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/embed"
+	"github.com/r4h4/article-helper/library"
+	"github.com/r4h4/article-helper/recorder"
+	"github.com/r4h4/article-helper/recording"
+	"github.com/r4h4/article-helper/usage"
+)
+
+// runDevices lists capture devices, or persists the device to record from.
+//
+//	devices           list available devices
+//	devices select    pick a device interactively
+//	devices use NAME  record from NAME ("default" resets to the system default)
+func runDevices(cfg *config.Config, configPath string, args []string) error {
+	if len(args) == 0 {
+		devices, err := recorder.ListDevices()
+		if err != nil {
+			return fmt.Errorf("listing devices: %w", err)
+		}
+		for _, d := range devices {
+			marker := " "
+			if d.Name == cfg.Device {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, d)
+		}
+		if cfg.Device == "" {
+			fmt.Println("Recording from the system default device")
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "select":
+		devices, err := recorder.ListDevices()
+		if err != nil {
+			return fmt.Errorf("listing devices: %w", err)
+		}
+		if len(devices) == 0 {
+			return fmt.Errorf("no capture devices found")
+		}
+
+		prompt := promptui.Select{
+			Label: "Select an input device",
+			Items: devices,
+		}
+		i, _, err := prompt.Run()
+		if err != nil {
+			return fmt.Errorf("prompt failed: %w", err)
+		}
+		cfg.Device = devices[i].Name
+	case "use":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: devices use <name>")
+		}
+		cfg.Device = args[1]
+		if cfg.Device == "default" {
+			cfg.Device = ""
+		}
+	default:
+		return fmt.Errorf("unknown devices command: %s", args[0])
+	}
+
+	if err := cfg.Save(configPath); err != nil {
+		return err
+	}
+	if cfg.Device == "" {
+		fmt.Println("Recording from the system default device")
+	} else {
+		fmt.Printf("Recording from %s\n", cfg.Device)
+	}
+	return nil
+}
+
+// runRepair fixes the header sizes of WAV files left behind by interrupted recordings
+func runRepair(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: repair <wav>...")
+	}
+
+	for _, path := range args {
+		result, err := recorder.RepairWAV(path)
+		if err != nil {
+			return fmt.Errorf("repairing %s: %w", path, err)
+		}
+
+		if result.Repaired {
+			fmt.Printf("%s: repaired, header declared %d bytes of audio, found %d (%s)\n",
+				path, result.DeclaredSize, result.DataSize, result.Duration.Round(time.Millisecond))
+		} else {
+			fmt.Printf("%s: header is valid (%s)\n", path, result.Duration.Round(time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// dateLayout is the layout of the -since and -until flags
+const dateLayout = "2006-01-02"
+
+// filterFlags registers the library filter flags on fs
+func filterFlags(fs *flag.FlagSet) func() (library.Filter, error) {
+	since := fs.String("since", "", "Only recordings on or after this date (YYYY-MM-DD)")
+	until := fs.String("until", "", "Only recordings on or before this date (YYYY-MM-DD)")
+	tag := fs.String("tag", "", "Only recordings with this tag")
+	category := fs.String("category", "", "Only recordings in this category")
+
+	return func() (library.Filter, error) {
+		f := library.Filter{Tag: *tag, Category: *category}
+		if *since != "" {
+			date, err := time.ParseInLocation(dateLayout, *since, time.Local)
+			if err != nil {
+				return f, fmt.Errorf("invalid -since date: %w", err)
+			}
+			f.Since = date
+		}
+		if *until != "" {
+			date, err := time.ParseInLocation(dateLayout, *until, time.Local)
+			if err != nil {
+				return f, fmt.Errorf("invalid -until date: %w", err)
+			}
+			f.Until = date.AddDate(0, 0, 1)
+		}
+		return f, nil
+	}
+}
+
+// runList lists the recordings in the library, newest first
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	filter := filterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+
+	lib, err := library.Open(recordingsDir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, e := range lib.List(f) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Date.Format("2006-01-02 15:04"), e.ID, e.Category, strings.Join(e.Tags, ", "))
+	}
+	return w.Flush()
+}
+
+// runSearch searches the library and prints a highlighted snippet per hit
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	filter := filterFlags(fs)
+	limit := fs.Int("n", 10, "Maximum number of results")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: search [-since date] [-until date] [-tag tag] [-category category] <query>")
+	}
+	if *limit < 1 {
+		return fmt.Errorf("-n must be at least 1")
+	}
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+
+	lib, err := library.Open(recordingsDir)
+	if err != nil {
+		return err
+	}
+
+	results := lib.Search(strings.Join(fs.Args(), " "), f)
+	if len(results) == 0 {
+		fmt.Println("No recordings found")
+		return nil
+	}
+	if len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	mark := func(s string) string { return s }
+	if isTerminal(os.Stdout) {
+		mark = func(s string) string { return "\033[1m" + s + "\033[0m" }
+	}
+	for _, r := range results {
+		fmt.Printf("%s  %s\n", r.Entry.Date.Format("2006-01-02 15:04"), r.Entry.ID)
+		text := r.Entry.Transcript
+		if text == "" {
+			text = r.Entry.Summary
+		}
+		fmt.Printf("  %s\n\n", library.Snippet(text, r.Terms, 160, mark))
+	}
+	return nil
+}
+
+// runShow prints a recording from the library
+func runShow(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: show <id>")
+	}
+
+	lib, err := library.Open(recordingsDir)
+	if err != nil {
+		return err
+	}
+	e, err := lib.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", e.Headline)
+	fmt.Printf("Date:     %s\n", e.Date.Format("2006-01-02 15:04"))
+	fmt.Printf("Folder:   %s\n", filepath.Join(lib.Root(), e.ID))
+	if e.Category != "" {
+		fmt.Printf("Category: %s\n", e.Category)
+	}
+	if len(e.Tags) > 0 {
+		fmt.Printf("Tags:     %s\n", strings.Join(e.Tags, ", "))
+	}
+	fmt.Printf("\nSummary:\n%s\n\nTranscript:\n%s\n", e.Summary, e.Transcript)
+	return nil
+}
+
+// runReindex rebuilds the library from the recording folders, e.g. for
+// recordings made before the library existed
+func runReindex(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: reindex")
+	}
+
+	dirs, err := os.ReadDir(recordingsDir)
+	if err != nil {
+		return fmt.Errorf("reading recordings: %w", err)
+	}
+
+	var entries []*library.Entry
+	for _, d := range dirs {
+		// Hidden folders hold server jobs, not recordings
+		if !d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+		e, err := readEntry(filepath.Join(recordingsDir, d.Name()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", d.Name(), err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	lib, err := library.Open(recordingsDir)
+	if err != nil {
+		return err
+	}
+	if err := lib.Rebuild(entries); err != nil {
+		return err
+	}
+	fmt.Printf("Indexed %d recordings\n", len(entries))
+	return nil
+}
+
+// readEntry builds a library entry from the files in a recording folder.
+// Folders saved before metadata.json existed are dated by their name.
+func readEntry(dir string) (*library.Entry, error) {
+	name := filepath.Base(dir)
+	m, err := recording.LoadMetadata(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		if len(name) < len(recording.TimestampLayout) {
+			return nil, fmt.Errorf("not a recording folder")
+		}
+		m = &recording.Metadata{
+			Timestamp: name[:len(recording.TimestampLayout)],
+			Headline:  strings.TrimPrefix(name[len(recording.TimestampLayout):], "_"),
+		}
+		if m.Date, err = time.ParseInLocation(recording.TimestampLayout, m.Timestamp, time.Local); err != nil {
+			return nil, fmt.Errorf("not a recording folder")
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	read := func(fileName string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, fileName))
+		return strings.TrimSpace(string(data))
+	}
+	return &library.Entry{
+		ID:         name,
+		Date:       m.Date,
+		Headline:   m.Headline,
+		Tags:       m.Tags,
+		Category:   m.Category,
+		Summary:    read(fmt.Sprintf("summary_%s.txt", m.Timestamp)),
+		Transcript: read(fmt.Sprintf("cleaned_transcription_%s.txt", m.Timestamp)),
+	}, nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runEmbed embeds the transcripts of library recordings that are not in the
+// vector store yet, or of all recordings with -all, e.g. after changing the model
+func runEmbed(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("embed", flag.ContinueOnError)
+	all := fs.Bool("all", false, "Rebuild the vector store from scratch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	lib, err := library.Open(recordingsDir)
+	if err != nil {
+		return err
+	}
+	store, err := embed.OpenStore(recordingsDir)
+	if err != nil {
+		return err
+	}
+	if *all {
+		if err := store.Reset(); err != nil {
+			return err
+		}
+	}
+
+	client := newEmbedClient(cfg)
+	count := 0
+	for _, e := range lib.List(library.Filter{}) {
+		if e.Transcript == "" || store.Has(e.ID) {
+			continue
+		}
+		slog.Info("Embedding recording", "id", e.ID)
+		if err := store.Index(ctx, client, e.ID, e.Transcript); err != nil {
+			return fmt.Errorf("embedding %s: %w", e.ID, err)
+		}
+		count++
+	}
+	fmt.Printf("Embedded %d recordings\n", count)
+	return nil
+}
+
+// runAsk answers a question from the most relevant parts of past recordings
+func runAsk(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("ask", flag.ContinueOnError)
+	filter := filterFlags(fs)
+	limit := fs.Int("n", 8, "Number of transcript excerpts to answer from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: ask [-since date] [-until date] [-tag tag] [-category category] <question>")
+	}
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("OPENAI_API_KEY environment variable is not set")
+	}
+
+	lib, err := library.Open(recordingsDir)
+	if err != nil {
+		return err
+	}
+	store, err := embed.OpenStore(recordingsDir)
+	if err != nil {
+		return err
+	}
+
+	question := strings.Join(fs.Args(), " ")
+	tracker := usage.NewTracker(cfg.Prices)
+	client := newEmbedClient(cfg).WithTracker(tracker)
+	vectors, err := client.Embed(ctx, []string{question})
+	if err != nil {
+		return fmt.Errorf("embedding question: %w", err)
+	}
+
+	entries := make(map[string]*library.Entry)
+	for _, e := range lib.List(f) {
+		entries[e.ID] = e
+	}
+	matches := store.Search(vectors[0], *limit, func(id string) bool { return entries[id] != nil })
+	if len(matches) == 0 {
+		return fmt.Errorf("no embedded recordings found, record with -embed or run the embed command first")
+	}
+
+	excerpts := make([]editor.Excerpt, len(matches))
+	for i, m := range matches {
+		excerpts[i] = editor.Excerpt{
+			Source: m.Recording,
+			Date:   entries[m.Recording].Date.Format("2006-01-02 15:04"),
+			Text:   m.Text,
+		}
+	}
+
+	ed := editor.NewAIEditor(apiKey, openAIBaseURL()+"/chat/completions")
+	ed.TrackUsage(tracker)
+	result, err := ed.Answer(ctx, question, excerpts)
+	if err != nil {
+		return fmt.Errorf("answering question: %w", err)
+	}
+
+	fmt.Printf("%s\n", result.Answer)
+	if len(result.Sources) > 0 {
+		fmt.Printf("\nSources:\n")
+	}
+	for _, n := range result.Sources {
+		if n < 1 || n > len(excerpts) {
+			continue
+		}
+		ex := excerpts[n-1]
+		fmt.Printf("[%d] %s  %s\n", n, ex.Date, filepath.Join(recordingsDir, ex.Source))
+	}
+	printUsage(tracker.Summary())
+	return nil
+}
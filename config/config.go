@@ -0,0 +1,198 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/r4h4/article-helper/usage"
+)
+
+const (
+	appDir     = "article-helper"
+	configFile = "config.json"
+)
+
+// Config holds the user settings that persist between runs
+type Config struct {
+	// Device is the capture device to record from (default: system default)
+	Device string `json:"device,omitempty"`
+
+	// UploadFormat is the format recordings are encoded to before upload (default: flac)
+	UploadFormat string `json:"upload_format,omitempty"`
+
+	// Glossary is the path of a glossary file applied to every recording
+	Glossary string `json:"glossary,omitempty"`
+
+	// Taxonomy lists the categories recordings are filed under when tagging
+	Taxonomy []string `json:"taxonomy,omitempty"`
+
+	// EmbeddingEndpoint is an OpenAI compatible embeddings endpoint, e.g. of a
+	// local server (default: OpenAI)
+	EmbeddingEndpoint string `json:"embedding_endpoint,omitempty"`
+
+	// EmbeddingModel is the model used for semantic search (default: text-embedding-3-small)
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+
+	// SkipSteps names pipeline steps not to run, e.g. ["rename"] to keep
+	// recording folders named by timestamp. Skipping "headline" skips the
+	// rename too, as there is no slug to name the folder after.
+	SkipSteps []string `json:"skip_steps,omitempty"`
+
+	// Plugins are executables run as pipeline steps
+	Plugins []Plugin `json:"plugins,omitempty"`
+
+	// Profiles are named sets of destinations for the results of a run, chosen
+	// with -profile. The "default" profile is used if none is chosen.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// Prices overrides the built-in prices per model used for cost accounting, e.g.
+	// {"gpt-4o": {"input": 2.5, "output": 10}, "whisper-1": {"per_minute": 0.006}}
+	Prices usage.Prices `json:"prices,omitempty"`
+}
+
+// Plugin is an executable run as a pipeline step. It receives the state of
+// the pipeline as JSON on stdin and prints the fields it changes as a JSON
+// object to stdout, e.g. {"summary": "..."}.
+type Plugin struct {
+	// Name identifies the plugin in errors and the skip list
+	Name string `json:"name"`
+
+	// Command is the executable and its arguments, run in the recording folder
+	Command []string `json:"command"`
+
+	// Before or After names the step the plugin runs before or after, e.g.
+	// "save" (default: after the last step)
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+
+	// Timeout is how long the plugin may run, e.g. "30s" (default: 1m)
+	Timeout string `json:"timeout,omitempty"`
+
+	// OnError is "abort" to fail the run when the plugin fails (default), or
+	// "warn" to log the error and continue
+	OnError string `json:"on_error,omitempty"`
+
+	// Requires and Produces name the state fields the plugin reads and sets,
+	// e.g. ["cleaned_transcription"], so it is checked to run in order. Only
+	// the fields it produces are taken from its output.
+	Requires []string `json:"requires,omitempty"`
+	Produces []string `json:"produces,omitempty"`
+}
+
+// DefaultProfile is used if no profile is chosen
+const DefaultProfile = "default"
+
+// Profile selects where the results of a run are sent
+type Profile struct {
+	// Exports send the results to other apps after they are saved
+	Exports []Export `json:"exports,omitempty"`
+
+	// Deliveries send the summary and action items to people
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+}
+
+// Export is a destination of the results of a run
+type Export struct {
+	// Type is "obsidian", "notion" or "webhook"
+	Type string `json:"type"`
+
+	// Vault is the directory of an Obsidian vault, and Folder the folder
+	// within it the notes are written to (default: the vault root)
+	Vault  string `json:"vault,omitempty"`
+	Folder string `json:"folder,omitempty"`
+
+	// DatabaseID or PageID is the parent of the Notion pages, created with the
+	// token in the environment variable TokenEnv (default: NOTION_TOKEN)
+	DatabaseID    string `json:"database_id,omitempty"`
+	PageID        string `json:"page_id,omitempty"`
+	TitleProperty string `json:"title_property,omitempty"`
+	TokenEnv      string `json:"token_env,omitempty"`
+
+	// URL receives the results of a webhook export as JSON. Environment
+	// variables in the Headers are expanded, e.g. {"Authorization": "Bearer $TOKEN"}.
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Delivery sends the summary and action items by email or to a chat
+type Delivery struct {
+	// Type is "email", or "slack", "matrix" or "teams" for their incoming webhooks
+	Type string `json:"type"`
+
+	// To are the recipients of an email sent from From through the SMTP
+	// server at SMTPServer, e.g. "smtp.example.com:587". If SMTPUsername is
+	// set, it logs in with the password in the environment variable
+	// PasswordEnv (default: SMTP_PASSWORD).
+	To           []string `json:"to,omitempty"`
+	From         string   `json:"from,omitempty"`
+	SMTPServer   string   `json:"smtp_server,omitempty"`
+	SMTPUsername string   `json:"smtp_username,omitempty"`
+	PasswordEnv  string   `json:"password_env,omitempty"`
+
+	// URL is the incoming webhook of a chat
+	URL string `json:"url,omitempty"`
+
+	// TextTemplate and HTMLTemplate are paths of Go templates replacing the
+	// built-in ones for the message bodies
+	TextTemplate string `json:"text_template,omitempty"`
+	HTMLTemplate string `json:"html_template,omitempty"`
+}
+
+// Profile returns the profile called name, or the default profile if name is
+// empty, which may not be configured
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		return c.Profiles[DefaultProfile], nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("no profile %q in config", name)
+	}
+	return p, nil
+}
+
+// DefaultPath returns the location of the config file in the user's config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating config directory: %w", err)
+	}
+	return filepath.Join(dir, appDir, configFile), nil
+}
+
+// Load reads the config from path. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the config to path, creating its directory if needed
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
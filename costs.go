@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/r4h4/article-helper/library"
+	"github.com/r4h4/article-helper/recording"
+	"github.com/r4h4/article-helper/usage"
+)
+
+// printUsage prints the API usage and cost of a run
+func printUsage(summary *usage.Summary) {
+	if len(summary.Models) == 0 {
+		return
+	}
+
+	fmt.Println("\nUsage:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, m := range summary.Models {
+		fmt.Fprintf(w, "  %s\t%s\t$%.4f\n", m.Model, formatAmount(m), m.Cost)
+	}
+	fmt.Fprintf(w, "  Total\t\t$%.4f\n", summary.Cost)
+	w.Flush()
+}
+
+// formatAmount describes what a model was used for, e.g. "1200 tokens" or "3.5 min audio"
+func formatAmount(m usage.Model) string {
+	if m.AudioSeconds > 0 {
+		return fmt.Sprintf("%.1f min audio", m.AudioSeconds/60)
+	}
+	return fmt.Sprintf("%d tokens", m.PromptTokens+m.CompletionTokens)
+}
+
+// runCosts sums the API spend of the recordings in the library by day and model
+func runCosts(args []string) error {
+	fs := flag.NewFlagSet("costs", flag.ContinueOnError)
+	filter := filterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+
+	lib, err := library.Open(recordingsDir)
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		day   string
+		model string
+	}
+	totals := make(map[key]*usage.Model)
+	var total float64
+	for _, e := range lib.List(f) {
+		m, err := recording.LoadMetadata(filepath.Join(lib.Root(), e.ID))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if m.Usage == nil {
+			continue
+		}
+
+		day := e.Date.Format(dateLayout)
+		for _, model := range m.Usage.Models {
+			k := key{day, model.Model}
+			if totals[k] == nil {
+				totals[k] = &usage.Model{Model: model.Model}
+			}
+			totals[k].Add(model)
+			total += model.Cost
+		}
+	}
+	if len(totals) == 0 {
+		fmt.Println("No usage recorded")
+		return nil
+	}
+
+	keys := make([]key, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].day != keys[j].day {
+			return keys[i].day < keys[j].day
+		}
+		return keys[i].model < keys[j].model
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DAY\tMODEL\tREQUESTS\tAMOUNT\tCOST")
+	for _, k := range keys {
+		m := totals[k]
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t$%.4f\n", k.day, k.model, m.Requests, formatAmount(*m), m.Cost)
+	}
+	fmt.Fprintf(w, "Total\t\t\t\t$%.4f\n", total)
+	return w.Flush()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/r4h4/article-helper/config"
+	"github.com/r4h4/article-helper/delivery"
+	"github.com/r4h4/article-helper/editor"
+)
+
+const defaultSMTPPasswordEnv = "SMTP_PASSWORD"
+
+// DeliverStep sends the summary and action items to the recipients of the profile
+type DeliverStep struct {
+	Deliverers []delivery.Deliverer
+}
+
+func (s *DeliverStep) Name() string      { return "deliver" }
+func (s *DeliverStep) Requires() []Field { return []Field{FieldSummary} }
+func (s *DeliverStep) Produces() []Field { return nil }
+
+// Execute runs every deliverer, even if an earlier one fails
+func (s *DeliverStep) Execute(ctx context.Context, state *State) error {
+	m := state.Metadata()
+	msg := &delivery.Message{
+		Title:   m.Headline,
+		Date:    m.Date,
+		Summary: state.Summary,
+		Actions: state.Actions,
+	}
+	if msg.Title == "" {
+		msg.Title = "Recording " + state.Timestamp
+	}
+	msg.Subject = state.Headlines.Best(editor.HeadlineEmail)
+	if msg.Subject == "" {
+		msg.Subject = msg.Title
+	}
+
+	var errs []error
+	for _, d := range s.Deliverers {
+		if err := d.Deliver(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Name(), err))
+			continue
+		}
+		slog.Info("Delivered summary", "recording", state.Timestamp, "deliverer", d.Name())
+	}
+	return errors.Join(errs...)
+}
+
+// newDeliverer returns the deliverer configured by d. Its requests are not
+// logged, as chat webhook URLs carry their secret in the path.
+func newDeliverer(d config.Delivery) (delivery.Deliverer, error) {
+	templates, err := delivery.LoadTemplates(d.TextTemplate, d.HTMLTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("%s delivery: %w", d.Type, err)
+	}
+
+	switch d.Type {
+	case "email":
+		cfg := delivery.EmailConfig{
+			Server:    d.SMTPServer,
+			Username:  d.SMTPUsername,
+			From:      d.From,
+			To:        d.To,
+			Templates: templates,
+		}
+		if d.SMTPUsername != "" {
+			passwordEnv := d.PasswordEnv
+			if passwordEnv == "" {
+				passwordEnv = defaultSMTPPasswordEnv
+			}
+			if cfg.Password = os.Getenv(passwordEnv); cfg.Password == "" {
+				return nil, fmt.Errorf("email delivery: %s environment variable is not set", passwordEnv)
+			}
+		}
+		email, err := delivery.NewEmail(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("email delivery: %w", err)
+		}
+		return email, nil
+	case delivery.ChatSlack, delivery.ChatMatrix, delivery.ChatTeams:
+		chat, err := delivery.NewChat(delivery.ChatConfig{Style: d.Type, URL: d.URL, Templates: templates})
+		if err != nil {
+			return nil, fmt.Errorf("%s delivery: %w", d.Type, err)
+		}
+		return chat, nil
+	}
+	return nil, fmt.Errorf("unknown delivery type %q, expected email, slack, matrix or teams", d.Type)
+}
@@ -0,0 +1,82 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/r4h4/article-helper/internal/jsonapi"
+)
+
+// Chat styles, named after the chat apps whose incoming webhooks they post to
+const (
+	ChatSlack  = "slack"
+	ChatMatrix = "matrix" // E.g. a generic webhook of matrix-hookshot
+	ChatTeams  = "teams"
+)
+
+// ChatConfig holds the configuration for chat delivery
+type ChatConfig struct {
+	Style string // ChatSlack, ChatMatrix or ChatTeams
+	URL   string // Of the incoming webhook, which decides the channel or room
+
+	Templates *Templates // Default: the built-in templates
+
+	// Transport sends the requests, default http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Chat posts messages to the incoming webhook of a chat app
+type Chat struct {
+	config ChatConfig
+	client *http.Client
+}
+
+// NewChat creates a new chat deliverer
+func NewChat(config ChatConfig) (*Chat, error) {
+	switch config.Style {
+	case ChatSlack, ChatMatrix, ChatTeams:
+	default:
+		return nil, fmt.Errorf("unknown chat style %q", config.Style)
+	}
+	if config.URL == "" {
+		return nil, fmt.Errorf("no webhook URL for %s", config.Style)
+	}
+	if config.Templates == nil {
+		templates, err := LoadTemplates("", "")
+		if err != nil {
+			return nil, err
+		}
+		config.Templates = templates
+	}
+	return &Chat{
+		config: config,
+		client: &http.Client{Timeout: requestTimeout, Transport: config.Transport},
+	}, nil
+}
+
+func (c *Chat) Name() string { return c.config.Style }
+
+func (c *Chat) Deliver(ctx context.Context, msg *Message) error {
+	text, html, err := c.config.Templates.Render(msg)
+	if err != nil {
+		return err
+	}
+
+	var payload any
+	switch c.config.Style {
+	case ChatSlack:
+		payload = map[string]string{"text": text}
+	case ChatMatrix:
+		payload = map[string]string{"text": text, "html": html}
+	case ChatTeams:
+		payload = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  msg.Subject,
+			"title":    msg.Subject,
+			"text":     text,
+		}
+	}
+	return jsonapi.Send(ctx, c.client, http.MethodPost, c.config.URL, nil, payload, nil)
+}
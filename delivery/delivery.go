@@ -0,0 +1,100 @@
+// Package delivery sends the summaries of recordings to people by email and chat
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/r4h4/article-helper/editor"
+)
+
+// Timeout of each request to a chat webhook
+const requestTimeout = 30 * time.Second
+
+// Deliverer sends the summary of a recording to its recipients
+type Deliverer interface {
+	// Name identifies the deliverer in logs and errors, e.g. "email"
+	Name() string
+	Deliver(ctx context.Context, msg *Message) error
+}
+
+// Message is the summary of a recording as it is delivered
+type Message struct {
+	Subject string // E.g. the email style headline
+	Title   string
+	Date    time.Time
+	Summary string
+	Actions *editor.ActionsResponse // Nil if actions were not extracted
+}
+
+// SummaryPoints returns the lines of the summary without bullet points
+func (m *Message) SummaryPoints() []string {
+	var points []string
+	for _, line := range strings.Split(m.Summary, "\n") {
+		line = strings.TrimSpace(line)
+		for _, bullet := range []string{"- ", "* ", "• "} {
+			line = strings.TrimPrefix(line, bullet)
+		}
+		if line != "" {
+			points = append(points, line)
+		}
+	}
+	return points
+}
+
+// Templates render the plain text and HTML bodies of a message
+type Templates struct {
+	Text *texttemplate.Template
+	HTML *htmltemplate.Template
+}
+
+// LoadTemplates parses the template files at textPath and htmlPath. The
+// built-in templates are used for empty paths.
+func LoadTemplates(textPath, htmlPath string) (*Templates, error) {
+	textSource, htmlSource := DefaultTextTemplate, DefaultHTMLTemplate
+	if textPath != "" {
+		data, err := os.ReadFile(textPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading text template: %w", err)
+		}
+		textSource = string(data)
+	}
+	if htmlPath != "" {
+		data, err := os.ReadFile(htmlPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading HTML template: %w", err)
+		}
+		htmlSource = string(data)
+	}
+
+	text, err := texttemplate.New("text").Parse(textSource)
+	if err != nil {
+		return nil, fmt.Errorf("parsing text template: %w", err)
+	}
+	html, err := htmltemplate.New("html").Parse(htmlSource)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTML template: %w", err)
+	}
+	return &Templates{Text: text, HTML: html}, nil
+}
+
+// Render returns the plain text and HTML bodies of msg
+func (t *Templates) Render(msg *Message) (text, html string, err error) {
+	var b bytes.Buffer
+	if err := t.Text.Execute(&b, msg); err != nil {
+		return "", "", fmt.Errorf("rendering text: %w", err)
+	}
+	text = b.String()
+
+	b.Reset()
+	if err := t.HTML.Execute(&b, msg); err != nil {
+		return "", "", fmt.Errorf("rendering HTML: %w", err)
+	}
+	return text, b.String(), nil
+}
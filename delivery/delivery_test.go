@@ -0,0 +1,238 @@
+package delivery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/r4h4/article-helper/editor"
+)
+
+func testMessage() *Message {
+	return &Message{
+		Subject: "Update: enterprise pricing for next quarter",
+		Title:   "Enterprise Pricing Update",
+		Date:    time.Date(2026, 10, 19, 14, 3, 0, 0, time.UTC),
+		Summary: "- Enterprise pricing rises by 10%\n- Existing <customers> keep their price",
+		Actions: &editor.ActionsResponse{
+			ActionItems: []editor.ActionItem{{Owner: "Alice", Task: "Email the customers", Due: "Friday"}},
+			Decisions:   []editor.Decision{{Decision: "Raise the price"}},
+		},
+	}
+}
+
+// smtpSink is a local SMTP server keeping the messages it receives
+type smtpSink struct {
+	addr string
+
+	mu         sync.Mutex
+	recipients []string
+	data       []string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	sink := &smtpSink{addr: l.Addr().String()}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost sink")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			s.recipients = append(s.recipients, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			s.mu.Lock()
+			s.data = append(s.data, data.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	sink := newSMTPSink(t)
+	email, err := NewEmail(EmailConfig{
+		Server: sink.addr,
+		From:   "Article Helper <notes@example.com>",
+		To:     []string{"alice@example.com", "Bob <bob@example.com>"},
+	})
+	if err != nil {
+		t.Fatalf("NewEmail failed: %v", err)
+	}
+	if err := email.Deliver(context.Background(), testMessage()); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if strings.Join(sink.recipients, ",") != "alice@example.com,bob@example.com" || len(sink.data) != 1 {
+		t.Fatalf("Expected one email to both recipients, got %v", sink.recipients)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(sink.data[0]))
+	if err != nil {
+		t.Fatalf("Parsing email: %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); subject != "Update: enterprise pricing for next quarter" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected a multipart/alternative email, got %q", m.Header.Get("Content-Type"))
+	}
+
+	bodies := make(map[string]string)
+	parts := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Reading part: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = strings.ReplaceAll(string(body), "\r\n", "\n")
+	}
+	if text := bodies["text/plain"]; !strings.Contains(text, "- Alice: Email the customers (due Friday)\n") || !strings.Contains(text, "- Existing <customers> keep their price\n") {
+		t.Errorf("Unexpected plain text body:\n%s", text)
+	}
+	if html := bodies["text/html"]; !strings.Contains(html, "<li><strong>Alice</strong>: Email the customers (due Friday)</li>") || !strings.Contains(html, "Existing &lt;customers&gt;") {
+		t.Errorf("Unexpected HTML body:\n%s", html)
+	}
+
+	if _, err := NewEmail(EmailConfig{Server: sink.addr, From: "notes@example.com"}); err == nil {
+		t.Error("Expected an error without recipients")
+	}
+}
+
+func TestChat(t *testing.T) {
+	var mu sync.Mutex
+	payloads := make(map[string]map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Error decoding request: %v", err)
+		}
+		mu.Lock()
+		payloads[r.URL.Path] = payload
+		mu.Unlock()
+		if r.URL.Path == "/broken" {
+			http.Error(w, "invalid_token", http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	for _, style := range []string{ChatSlack, ChatMatrix, ChatTeams} {
+		chat, err := NewChat(ChatConfig{Style: style, URL: server.URL + "/" + style})
+		if err != nil {
+			t.Fatalf("NewChat failed: %v", err)
+		}
+		if err := chat.Deliver(context.Background(), testMessage()); err != nil {
+			t.Errorf("Delivering to %s failed: %v", style, err)
+		}
+	}
+
+	if text := payloads["/slack"]["text"]; !strings.HasPrefix(text, "Enterprise Pricing Update (2026-10-19 14:03)\n") {
+		t.Errorf("Unexpected Slack text %q", text)
+	}
+	if html := payloads["/matrix"]["html"]; !strings.Contains(html, "<h1>Enterprise Pricing Update</h1>") || payloads["/matrix"]["text"] == "" {
+		t.Errorf("Unexpected Matrix payload %v", payloads["/matrix"])
+	}
+	if card := payloads["/teams"]; card["@type"] != "MessageCard" || card["title"] != "Update: enterprise pricing for next quarter" {
+		t.Errorf("Unexpected Teams card %v", card)
+	}
+
+	broken, _ := NewChat(ChatConfig{Style: ChatSlack, URL: server.URL + "/broken"})
+	if err := broken.Deliver(context.Background(), testMessage()); err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Errorf("Expected a status error, got %v", err)
+	}
+	if _, err := NewChat(ChatConfig{Style: "irc", URL: server.URL}); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "text.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Title}}: {{len .SummaryPoints}} points"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadTemplates(path, "")
+	if err != nil {
+		t.Fatalf("LoadTemplates failed: %v", err)
+	}
+	text, html, err := templates.Render(testMessage())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if text != "Enterprise Pricing Update: 2 points" || !strings.HasPrefix(html, "<!DOCTYPE html>") {
+		t.Errorf("Unexpected bodies %q and %q", text, html)
+	}
+
+	if err := os.WriteFile(path, []byte("{{.Title"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTemplates(path, ""); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
@@ -0,0 +1,188 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Timeout of the whole SMTP conversation, unless the context ends earlier
+const smtpTimeout = 60 * time.Second
+
+// EmailConfig holds the configuration for email delivery
+type EmailConfig struct {
+	// Server is the address of the SMTP server, e.g. smtp.example.com:587
+	Server string
+	// Username and Password log in to the server, if a username is set. The
+	// password is only sent over TLS or to localhost.
+	Username string
+	Password string
+
+	From string
+	To   []string
+
+	Templates *Templates // Default: the built-in templates
+}
+
+// Email sends messages with plain text and HTML bodies over SMTP
+type Email struct {
+	config EmailConfig
+}
+
+// NewEmail creates a new email deliverer
+func NewEmail(config EmailConfig) (*Email, error) {
+	if _, _, err := net.SplitHostPort(config.Server); err != nil {
+		return nil, fmt.Errorf("invalid SMTP server %q: %w", config.Server, err)
+	}
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", config.From, err)
+	}
+	if len(config.To) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	for _, to := range config.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+	}
+	if config.Templates == nil {
+		templates, err := LoadTemplates("", "")
+		if err != nil {
+			return nil, err
+		}
+		config.Templates = templates
+	}
+	return &Email{config: config}, nil
+}
+
+func (e *Email) Name() string { return "email" }
+
+func (e *Email) Deliver(ctx context.Context, msg *Message) error {
+	text, html, err := e.config.Templates.Render(msg)
+	if err != nil {
+		return err
+	}
+	data, err := e.compose(msg.Subject, text, html)
+	if err != nil {
+		return fmt.Errorf("composing email: %w", err)
+	}
+	return e.send(ctx, data)
+}
+
+// compose returns a multipart/alternative message with both bodies
+func (e *Email) compose(subject, text, html string) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	header := []string{
+		"From: " + e.config.From,
+		"To: " + strings.Join(e.config.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	for _, line := range header {
+		b.WriteString(line + "\r\n")
+	}
+	b.WriteString("\r\n")
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
+
+func (e *Email) send(ctx context.Context, data []byte) error {
+	host, _, _ := net.SplitHostPort(e.config.Server)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.config.Server)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", e.config.Server, err)
+	}
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("connecting to %s: %w", e.config.Server, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if e.config.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.config.Username, e.config.Password, host)); err != nil {
+			return fmt.Errorf("logging in: %w", err)
+		}
+	}
+
+	if err := c.Mail(address(e.config.From)); err != nil {
+		return fmt.Errorf("sending from %s: %w", e.config.From, err)
+	}
+	for _, to := range e.config.To {
+		if err := c.Rcpt(address(to)); err != nil {
+			return fmt.Errorf("sending to %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+	return c.Quit()
+}
+
+// address returns the bare address of e.g. "Alice <alice@example.com>",
+// which was validated in NewEmail
+func address(s string) string {
+	a, err := mail.ParseAddress(s)
+	if err != nil {
+		return s
+	}
+	return a.Address
+}
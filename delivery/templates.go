@@ -0,0 +1,45 @@
+package delivery
+
+const (
+	// DefaultTextTemplate renders the plain text body of a Message
+	DefaultTextTemplate = `{{.Title}}{{if not .Date.IsZero}} ({{.Date.Format "2006-01-02 15:04"}}){{end}}
+
+Summary:
+{{range .SummaryPoints}}- {{.}}
+{{end}}{{with .Actions}}{{if .ActionItems}}
+Action items:
+{{range .ActionItems}}- {{if .Owner}}{{.Owner}}: {{end}}{{.Task}}{{if .Due}} (due {{.Due}}){{end}}
+{{end}}{{end}}{{if .Decisions}}
+Decisions:
+{{range .Decisions}}- {{.Decision}}
+{{end}}{{end}}{{if .OpenQuestions}}
+Open questions:
+{{range .OpenQuestions}}- {{.Question}}{{if .Owner}} ({{.Owner}}){{end}}
+{{end}}{{end}}{{end}}`
+
+	// DefaultHTMLTemplate renders the HTML body of a Message
+	DefaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body>
+<h1>{{.Title}}</h1>
+{{if not .Date.IsZero}}<p>{{.Date.Format "2006-01-02 15:04"}}</p>
+{{end}}<h2>Summary</h2>
+<ul>
+{{range .SummaryPoints}}<li>{{.}}</li>
+{{end}}</ul>
+{{with .Actions}}{{if .ActionItems}}<h2>Action items</h2>
+<ul>
+{{range .ActionItems}}<li>{{if .Owner}}<strong>{{.Owner}}</strong>: {{end}}{{.Task}}{{if .Due}} (due {{.Due}}){{end}}</li>
+{{end}}</ul>
+{{end}}{{if .Decisions}}<h2>Decisions</h2>
+<ul>
+{{range .Decisions}}<li>{{.Decision}}</li>
+{{end}}</ul>
+{{end}}{{if .OpenQuestions}}<h2>Open questions</h2>
+<ul>
+{{range .OpenQuestions}}<li>{{.Question}}{{if .Owner}} ({{.Owner}}){{end}}</li>
+{{end}}</ul>
+{{end}}{{end}}</body>
+</html>
+`
+)
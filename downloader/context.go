@@ -2,27 +2,35 @@ package downloader
 
 import (
 	"context"
+	"log/slog"
 	"os"
 	"os/signal"
 )
 
 // ContextForSignal returns a context object which is cancelled when a signal
-// is received. It returns nil if no signal parameter is provided
+// is received. A second signal exits the process immediately. It returns nil
+// if no signal parameter is provided
 func ContextForSignal(signals ...os.Signal) context.Context {
 	if len(signals) == 0 {
 		return nil
 	}
 
-	ch := make(chan os.Signal)
+	// Buffered, as signals are dropped when the receiver is not ready
+	ch := make(chan os.Signal, 1)
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Send message on channel when signal received
 	signal.Notify(ch, signals...)
 
-	// When any signal received, call cancel
+	// When any signal received, call cancel; force quit on the second
 	go func() {
 		<-ch
+		slog.Warn("Interrupted, stopping. Press Ctrl-C again to quit immediately")
 		cancel()
+
+		<-ch
+		slog.Error("Quitting without saving state")
+		os.Exit(130)
 	}()
 
 	// Return success
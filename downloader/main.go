@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -128,15 +129,17 @@ func Download(ctx context.Context, p io.Writer, model, out string) (string, erro
 			pct = DownloadReport(p, pct, count, resp.ContentLength)
 		default:
 			// Read body
+			// The last read may return data along with io.EOF
 			n, err := resp.Body.Read(data)
-			if err != nil {
-				DownloadReport(p, pct, count, resp.ContentLength)
-				return path, err
-			} else if m, err := w.Write(data[:n]); err != nil {
+			if m, err := w.Write(data[:n]); err != nil {
 				return path, err
 			} else {
 				count += int64(m)
 			}
+			if err != nil {
+				DownloadReport(p, pct, count, resp.ContentLength)
+				return path, err
+			}
 		}
 	}
 }
@@ -155,7 +158,7 @@ func IsModelDownloaded(model string, out string) bool {
 	// Check if the model is already downloaded
 	path := filepath.Join(out, filepath.Base(model))
 	if _, err := os.Stat(path); err == nil {
-		fmt.Printf("Model %s already downloaded\n", model)
+		slog.Info("Model already downloaded", "model", model)
 		return true
 	}
 	return false
@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestURLForModel(t *testing.T) {
+	for _, model := range []string{"ggml-tiny.en", "ggml-tiny.en.bin"} {
+		url, err := URLForModel(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := srcUrl + "/ggml-tiny.en.bin"; url != expected {
+			t.Errorf("Expected %s, got %s", expected, url)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	model := bytes.Repeat([]byte("ggml"), 50000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ggml-tiny.bin" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(model)))
+		w.Write(model)
+	}))
+	defer server.Close()
+	out := t.TempDir()
+
+	// Download reports the end of the body as io.EOF
+	var progress bytes.Buffer
+	path, err := Download(context.Background(), &progress, server.URL+"/ggml-tiny.bin", out)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, model) {
+		t.Errorf("Expected %s to hold the model (%v)", path, err)
+	}
+	if filepath.Dir(path) != out || !strings.HasPrefix(progress.String(), "Downloading") {
+		t.Errorf("Unexpected path %s or progress %q", path, progress.String())
+	}
+
+	t.Run("Skip", func(t *testing.T) {
+		var progress bytes.Buffer
+		path, err := Download(context.Background(), &progress, server.URL+"/ggml-tiny.bin", out)
+		if err != nil || path != "" || !strings.HasPrefix(progress.String(), "Skipping") {
+			t.Errorf("Expected the download to be skipped, got %q, %v, %q", path, err, progress.String())
+		}
+		if !IsModelDownloaded("ggml-tiny.bin", out) {
+			t.Error("Expected the model to be downloaded")
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := Download(context.Background(), io.Discard, server.URL+"/ggml-huge.bin", out)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Expected a 404 error, got %v", err)
+		}
+		if IsModelDownloaded("ggml-huge.bin", out) {
+			t.Error("Expected no file to be created")
+		}
+	})
+}
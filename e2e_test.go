@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/encoder"
+	"github.com/r4h4/article-helper/internal/fakeopenai"
+	"github.com/r4h4/article-helper/library"
+	"github.com/r4h4/article-helper/recording"
+)
+
+// writeSample writes a 16 kHz mono WAV file holding a second of tone between
+// half a second of silence on either side
+func writeSample(t *testing.T, path string) {
+	t.Helper()
+
+	const rate = 16000
+	samples := make([]int16, 2*rate)
+	for i := rate / 2; i < 3*rate/2; i++ {
+		samples[i] = int16(10000 * math.Sin(2*math.Pi*440*float64(i)/rate))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+2*len(samples)))
+	b.WriteString("WAVEfmt ")
+	for _, v := range []interface{}{uint32(16), uint16(1), uint16(1), uint32(rate), uint32(rate * 2), uint16(2), uint16(16)} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(2*len(samples)))
+	binary.Write(&b, binary.LittleEndian, samples)
+
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// chdirTemp changes into an empty directory until the test ends
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+// setupRun runs the test in an empty directory, since recordings are saved
+// relative to it, and returns the options of a pipeline on a sample WAV
+func setupRun(t *testing.T, server *fakeopenai.Server) pipelineOptions {
+	t.Helper()
+
+	dir := chdirTemp(t)
+	input := filepath.Join(dir, "sample.wav")
+	writeSample(t, input)
+
+	return pipelineOptions{
+		APIKey:  "test-api-key",
+		BaseURL: server.BaseURL(),
+		Input:   input,
+		Trim:    true,
+		Format:  encoder.FLAC,
+		Actions: true,
+		Tags:    true,
+		Quiet:   true,
+	}
+}
+
+func runTestPipeline(t *testing.T, opts pipelineOptions) (*State, error) {
+	t.Helper()
+
+	state, pipeline, err := newRun(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state, runPipeline(context.Background(), pipeline, state, nil)
+}
+
+func TestPipeline(t *testing.T) {
+	server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+	opts := setupRun(t, server)
+	opts.LogHTTP = true
+
+	state, err := runTestPipeline(t, opts)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+
+	if state.Headline != "Enterprise Pricing Update" {
+		t.Errorf("Unexpected headline %q", state.Headline)
+	}
+	if expected := filepath.Join(recordingsDir, state.Timestamp+"_enterprise-pricing-update"); state.OutFolder != expected {
+		t.Errorf("Expected the folder to be renamed to %s, got %s", expected, state.OutFolder)
+	}
+	if !strings.HasPrefix(state.CleanedTranscription, "We decided to raise the enterprise pricing") {
+		t.Errorf("Unexpected cleaned transcription %q", state.CleanedTranscription)
+	}
+	if len(state.Actions.ActionItems) != 1 || state.Tags.Category != "meeting" {
+		t.Errorf("Unexpected actions %+v or tags %+v", state.Actions, state.Tags)
+	}
+
+	ts := state.Timestamp
+	for _, name := range []string{
+		"recording_" + ts + ".wav",
+		"recording_" + ts + ".trimmed.flac",
+		"transcription_" + ts + ".txt",
+		"cleaned_transcription_" + ts + ".txt",
+		"summary_" + ts + ".txt",
+		"notes_" + ts + ".md",
+		"actions.json",
+		recording.MetadataFile,
+	} {
+		if _, err := os.Stat(filepath.Join(state.OutFolder, name)); err != nil {
+			t.Errorf("Expected %s to be saved: %v", name, err)
+		}
+	}
+
+	// Silence is trimmed from a copy before the FLAC is uploaded, keeping the recording
+	info, err := os.Stat(filepath.Join(state.OutFolder, "recording_"+ts+".wav"))
+	if err != nil || info.Size() != 44+2*2*16000 {
+		t.Errorf("Expected the recording to be kept, got %v bytes (%v)", info.Size(), err)
+	}
+	info, err = os.Stat(filepath.Join(state.OutFolder, "recording_"+ts+".trimmed.wav"))
+	if err != nil || info.Size() >= 44+2*2*16000 {
+		t.Errorf("Expected the upload to be trimmed, got %v bytes (%v)", info.Size(), err)
+	}
+	if state.UploadFile != "recording_"+ts+".trimmed.flac" {
+		t.Errorf("Unexpected upload file %q", state.UploadFile)
+	}
+	requests := server.Requests()
+	if len(requests) != 5 || requests[0].Path != "/v1/audio/transcriptions" || !bytes.Contains(requests[0].Body, []byte("fLaC")) {
+		t.Errorf("Expected a FLAC upload followed by 4 chat completions, got %d requests", len(requests))
+	}
+
+	// Requests before and after renaming the folder are logged to the same file
+	httpLog, err := os.ReadFile(filepath.Join(state.OutFolder, httpLogFile))
+	if err != nil {
+		t.Fatalf("Reading HTTP log: %v", err)
+	}
+	if n := bytes.Count(httpLog, []byte("\n")); n != 5 || bytes.Contains(httpLog, []byte(opts.APIKey)) {
+		t.Errorf("Expected 5 requests without the API key in the HTTP log, got %d:\n%s", n, httpLog)
+	}
+
+	m, err := recording.LoadMetadata(state.OutFolder)
+	if err != nil {
+		t.Fatalf("Loading metadata failed: %v", err)
+	}
+	if m.Headline != state.Headline || m.Language != "english" || m.Category != "meeting" {
+		t.Errorf("Unexpected metadata %+v", m)
+	}
+	if m.Slug != "enterprise-pricing-update" || len(m.Headlines) != 5 || m.Headlines.Best(editor.HeadlineEmail) != "Update: enterprise pricing for next quarter" {
+		t.Errorf("Unexpected headline candidates %+v with slug %q", m.Headlines, m.Slug)
+	}
+	if m.Usage == nil || len(m.Usage.Models) != 3 || m.Usage.Cost <= 0 {
+		t.Fatalf("Expected usage of 3 models, got %+v", m.Usage)
+	}
+	if whisper := m.Usage.Models[2]; whisper.Model != "whisper-1" || whisper.AudioSeconds != 1.6 {
+		t.Errorf("Unexpected transcription usage %+v", whisper)
+	}
+	if gpt := m.Usage.Models[1]; gpt.Model != "gpt-4o" || gpt.Requests != 2 || gpt.PromptTokens != 812+640 {
+		t.Errorf("Unexpected gpt-4o usage %+v", gpt)
+	}
+
+	lib, err := library.Open(recordingsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results := lib.Search("price list", library.Filter{Tag: "pricing"}); len(results) != 1 {
+		t.Errorf("Expected the recording in the library, got %v", results)
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	t.Run("TranscriptionRetried", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+		server.Inject("/v1/audio/transcriptions", fakeopenai.RateLimited, fakeopenai.ServerError)
+		opts := setupRun(t, server)
+
+		if _, err := runTestPipeline(t, opts); err != nil {
+			t.Fatalf("Expected the transcription to be retried, got %v", err)
+		}
+	})
+
+	t.Run("EditServerError", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+		server.Inject("/v1/chat/completions", fakeopenai.ServerError)
+		opts := setupRun(t, server)
+
+		_, err := runTestPipeline(t, opts)
+		if err == nil || !strings.HasPrefix(err.Error(), "edit: ") {
+			t.Errorf("Expected the edit step to fail, got %v", err)
+		}
+	})
+
+	t.Run("MalformedJSON", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+		server.Inject("/v1/chat/completions", fakeopenai.MalformedJSON)
+		opts := setupRun(t, server)
+
+		_, err := runTestPipeline(t, opts)
+		if err == nil || !strings.Contains(err.Error(), "unexpected OpenAI response (status 200)") {
+			t.Errorf("Expected a parse error, got %v", err)
+		}
+	})
+	t.Run("Cancelled", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+		server.Inject("/v1/chat/completions", fakeopenai.Timeout(time.Minute))
+		opts := setupRun(t, server)
+
+		state, pipeline, err := newRun(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(200*time.Millisecond, cancel)
+
+		start := time.Now()
+		err = runPipeline(ctx, pipeline, state, nil)
+		if !errors.Is(err, context.Canceled) || time.Since(start) > 10*time.Second {
+			t.Fatalf("Expected the request to be cancelled, got %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(state.OutFolder, checkpointFile))
+		if err != nil {
+			t.Fatalf("Expected the state to be saved: %v", err)
+		}
+		var saved checkpoint
+		if err := json.Unmarshal(data, &saved); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(saved.Completed, []string{"import", "trim", "encode", "transcribe"}) || saved.State.Transcription == "" {
+			t.Errorf("Unexpected checkpoint %+v", saved)
+		}
+	})
+}
@@ -2,14 +2,19 @@ package editor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+
+	"github.com/r4h4/article-helper/internal/jsonapi"
+	"github.com/r4h4/article-helper/usage"
 )
 
 type Agent interface {
-	Process(input string) (interface{}, error)
+	Process(ctx context.Context, input string) (interface{}, error)
 }
 
 type OpenAIAgent struct {
@@ -17,6 +22,12 @@ type OpenAIAgent struct {
 	URL    string
 	Model  string
 	Prompt string
+
+	// Tracker records the tokens used, if not nil
+	Tracker *usage.Tracker
+
+	// Transport sends the requests, default http.DefaultTransport
+	Transport http.RoundTripper
 }
 
 func NewOpenAIAgent(apiKey, model, url, prompt string) *OpenAIAgent {
@@ -28,7 +39,7 @@ func NewOpenAIAgent(apiKey, model, url, prompt string) *OpenAIAgent {
 	}
 }
 
-func (a *OpenAIAgent) Process(input string) (interface{}, error) {
+func (a *OpenAIAgent) Process(ctx context.Context, input string) (interface{}, error) {
 	query := fmt.Sprintf(a.Prompt, input)
 
 	requestBody := OpenAIRequest{
@@ -44,7 +55,7 @@ func (a *OpenAIAgent) Process(input string) (interface{}, error) {
 		return nil, fmt.Errorf("error marshaling request body: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", a.URL, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", a.URL, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
@@ -52,10 +63,10 @@ func (a *OpenAIAgent) Process(input string) (interface{}, error) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.APIKey))
 
-	client := &http.Client{}
+	client := &http.Client{Transport: a.Transport}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request to OpenAI: %v", err)
+		return nil, fmt.Errorf("error sending request to OpenAI: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -66,13 +77,15 @@ func (a *OpenAIAgent) Process(input string) (interface{}, error) {
 
 	var openAIResp OpenAIResponse
 	err = json.Unmarshal(body, &openAIResp)
+	if err == nil && len(openAIResp.Choices) == 0 {
+		err = fmt.Errorf("no choices in OpenAI response")
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling OpenAI response: %v. Response body: %s", err, string(body))
+		slog.Debug("Unexpected OpenAI response", "model", a.Model, "status", resp.StatusCode, "body", string(body))
+		return nil, fmt.Errorf("unexpected OpenAI response (status %d): %v. Response body: %s", resp.StatusCode, err, jsonapi.Excerpt(body))
 	}
 
-	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in OpenAI response. Response body: %s", string(body))
-	}
+	a.Tracker.AddTokens(a.Model, openAIResp.Usage.PromptTokens, openAIResp.Usage.CompletionTokens)
 
 	return openAIResp.Choices[0].Message.Content, nil
 }
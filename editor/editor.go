@@ -1,17 +1,31 @@
 package editor
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/r4h4/article-helper/usage"
 )
 
 const (
 	openAIURL = "https://api.openai.com/v1/chat/completions"
+
+	// Longer slugs are cut, as they name folders
+	maxSlugLength = 60
 )
 
 type AIEditor struct {
-	EditorAgent   Agent
-	HeadlineAgent Agent
+	EditorAgent    Agent
+	HeadlineAgent  Agent
+	SpeakerAgent   Agent
+	ActionsAgent   Agent
+	TagsAgent      Agent
+	TranslateAgent Agent
+	AnswerAgent    Agent
 }
 
 type OpenAIRequest struct {
@@ -30,6 +44,23 @@ type OpenAIResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// EditOptions add context to EditAndSummarize
+type EditOptions struct {
+	// Language of the transcript, e.g. "german". The output is kept in it.
+	Language string
+	// Glossary of names and terms with their correct spelling, one per line
+	Glossary string
+	// Instructions from the user, e.g. "shorter" or "more formal"
+	Instructions string
 }
 
 type EditorResponse struct {
@@ -37,8 +68,128 @@ type EditorResponse struct {
 	Summary              string `json:"summary"`
 }
 
+type SpeakerSegment struct {
+	Speaker string `json:"speaker"`
+	Text    string `json:"text"`
+}
+
+type SpeakerResponse struct {
+	Segments []SpeakerSegment `json:"segments"`
+}
+
+type ActionItem struct {
+	Owner string `json:"owner,omitempty"`
+	Task  string `json:"task"`
+	Due   string `json:"due,omitempty"`
+}
+
+type Decision struct {
+	Decision string `json:"decision"`
+}
+
+type OpenQuestion struct {
+	Question string `json:"question"`
+	Owner    string `json:"owner,omitempty"`
+}
+
+type ActionsResponse struct {
+	ActionItems   []ActionItem   `json:"action_items"`
+	Decisions     []Decision     `json:"decisions"`
+	OpenQuestions []OpenQuestion `json:"open_questions"`
+}
+
+type Entities struct {
+	People    []string `json:"people"`
+	Companies []string `json:"companies"`
+	Products  []string `json:"products"`
+}
+
+type TagsResponse struct {
+	Tags     []string `json:"tags"`
+	Entities Entities `json:"entities"`
+	Category string   `json:"category"`
+}
+
+// Excerpt is a part of a past recording used to answer a question
+type Excerpt struct {
+	Source string // Recording folder
+	Date   string
+	Text   string
+}
+
+type AnswerResponse struct {
+	Answer  string `json:"answer"`
+	Sources []int  `json:"sources"` // Numbers of the excerpts the answer is based on, starting at 1
+}
+
+// Headline styles
+const (
+	HeadlineTitle  = "title"  // Short human-readable title
+	HeadlineSEO    = "seo"    // Title for search engines
+	HeadlineSocial = "social" // Hook for a social media post
+	HeadlineEmail  = "email"  // Email subject line
+)
+
+// HeadlineStyles lists the styles headline candidates are created in
+var HeadlineStyles = []string{HeadlineTitle, HeadlineSEO, HeadlineSocial, HeadlineEmail}
+
+type Headline struct {
+	Style string `json:"style"`
+	Text  string `json:"text"`
+}
+
+// Headlines are headline candidates, ranked best first within each style
+type Headlines []Headline
+
+// Of returns the candidates of a style, best first
+func (h Headlines) Of(style string) []string {
+	var texts []string
+	for _, headline := range h {
+		if headline.Style == style {
+			texts = append(texts, headline.Text)
+		}
+	}
+	return texts
+}
+
+// Best returns the best candidate of a style, or "" if there is none
+func (h Headlines) Best(style string) string {
+	if texts := h.Of(style); len(texts) > 0 {
+		return texts[0]
+	}
+	return ""
+}
+
 type HeadlineResponse struct {
-	Headline string `json:"headline"`
+	Headlines Headlines `json:"headlines"`
+	// Slug names the recording folder, e.g. "enterprise-pricing-update"
+	Slug string `json:"slug"`
+}
+
+// Slugify turns text into lowercase words joined by hyphens, e.g. for folder names
+func Slugify(text string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	slug := b.String()
+	// Cut long slugs after a whole word
+	if len(slug) > maxSlugLength {
+		if i := strings.LastIndexByte(slug[:maxSlugLength+1], '-'); i > 0 {
+			return slug[:i]
+		}
+		return strings.ToValidUTF8(slug[:maxSlugLength], "")
+	}
+	return slug
 }
 
 func NewAIEditor(apiKey string, modelUrl string) *AIEditor {
@@ -47,15 +198,53 @@ func NewAIEditor(apiKey string, modelUrl string) *AIEditor {
 	}
 
 	return &AIEditor{
-		EditorAgent:   NewOpenAIAgent(apiKey, "gpt-4o", modelUrl, EditorPrompt),
-		HeadlineAgent: NewOpenAIAgent(apiKey, "gpt-3.5-turbo", modelUrl, HeadlinePrompt),
+		EditorAgent:    NewOpenAIAgent(apiKey, "gpt-4o", modelUrl, EditorPrompt),
+		HeadlineAgent:  NewOpenAIAgent(apiKey, "gpt-3.5-turbo", modelUrl, HeadlinePrompt),
+		SpeakerAgent:   NewOpenAIAgent(apiKey, "gpt-4o", modelUrl, SpeakerPrompt),
+		ActionsAgent:   NewOpenAIAgent(apiKey, "gpt-4o", modelUrl, ActionsPrompt),
+		TagsAgent:      NewOpenAIAgent(apiKey, "gpt-3.5-turbo", modelUrl, TagsPrompt),
+		TranslateAgent: NewOpenAIAgent(apiKey, "gpt-4o", modelUrl, TranslatePrompt),
+		AnswerAgent:    NewOpenAIAgent(apiKey, "gpt-4o", modelUrl, AnswerPrompt),
+	}
+}
+
+// TrackUsage records the tokens used by all agents in t
+func (e *AIEditor) TrackUsage(t *usage.Tracker) {
+	for _, agent := range e.agents() {
+		if a, ok := agent.(*OpenAIAgent); ok {
+			a.Tracker = t
+		}
 	}
 }
 
-func (e *AIEditor) EditAndSummarize(transcript string) (*EditorResponse, error) {
-	result, err := e.EditorAgent.Process(transcript)
+// UseTransport sends the requests of all agents through rt, e.g. to log them
+func (e *AIEditor) UseTransport(rt http.RoundTripper) {
+	for _, agent := range e.agents() {
+		if a, ok := agent.(*OpenAIAgent); ok {
+			a.Transport = rt
+		}
+	}
+}
+
+func (e *AIEditor) agents() []Agent {
+	return []Agent{e.EditorAgent, e.HeadlineAgent, e.SpeakerAgent, e.ActionsAgent, e.TagsAgent, e.TranslateAgent, e.AnswerAgent}
+}
+
+func (e *AIEditor) EditAndSummarize(ctx context.Context, transcript string, opts EditOptions) (*EditorResponse, error) {
+	input := fmt.Sprintf("<transcription>\n%s\n</transcription>", transcript)
+	if opts.Language != "" {
+		input += fmt.Sprintf("\n<language>%s</language>", opts.Language)
+	}
+	if opts.Glossary != "" {
+		input += fmt.Sprintf("\n<glossary>\n%s\n</glossary>", opts.Glossary)
+	}
+	if opts.Instructions != "" {
+		input += fmt.Sprintf("\n<instructions>%s</instructions>", opts.Instructions)
+	}
+
+	result, err := e.EditorAgent.Process(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("error processing with editor agent: %v", err)
+		return nil, fmt.Errorf("error processing with editor agent: %w", err)
 	}
 
 	var editorResp EditorResponse
@@ -67,10 +256,13 @@ func (e *AIEditor) EditAndSummarize(transcript string) (*EditorResponse, error)
 	return &editorResp, nil
 }
 
-func (e *AIEditor) CreateHeadline(summary string) (*HeadlineResponse, error) {
-	result, err := e.HeadlineAgent.Process(summary)
+// CreateHeadline returns up to n ranked headline candidates in each of the
+// HeadlineStyles and a slug for the folder name
+func (e *AIEditor) CreateHeadline(ctx context.Context, summary string, n int) (*HeadlineResponse, error) {
+	input := fmt.Sprintf("<summary>\n%s\n</summary>\n<candidates>%d</candidates>", summary, n)
+	result, err := e.HeadlineAgent.Process(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("error processing with headline agent: %v", err)
+		return nil, fmt.Errorf("error processing with headline agent: %w", err)
 	}
 
 	var headlineResp HeadlineResponse
@@ -78,6 +270,120 @@ func (e *AIEditor) CreateHeadline(summary string) (*HeadlineResponse, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshaling headline response: %v", err)
 	}
+	if headlineResp.Headlines.Best(HeadlineTitle) == "" {
+		return nil, fmt.Errorf("headline response has no %s", HeadlineTitle)
+	}
+	// The model does not always follow the slug rules
+	headlineResp.Slug = Slugify(headlineResp.Slug)
+	if headlineResp.Slug == "" {
+		headlineResp.Slug = Slugify(headlineResp.Headlines.Best(HeadlineTitle))
+	}
 
 	return &headlineResp, nil
 }
+
+func (e *AIEditor) AttributeSpeakers(ctx context.Context, transcript string) (*SpeakerResponse, error) {
+	result, err := e.SpeakerAgent.Process(ctx, transcript)
+	if err != nil {
+		return nil, fmt.Errorf("error processing with speaker agent: %w", err)
+	}
+
+	var speakerResp SpeakerResponse
+	err = json.Unmarshal([]byte(result.(string)), &speakerResp)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling speaker response: %v", err)
+	}
+
+	return &speakerResp, nil
+}
+
+func (e *AIEditor) ExtractActions(ctx context.Context, transcript string) (*ActionsResponse, error) {
+	result, err := e.ActionsAgent.Process(ctx, transcript)
+	if err != nil {
+		return nil, fmt.Errorf("error processing with actions agent: %w", err)
+	}
+
+	var actionsResp ActionsResponse
+	err = json.Unmarshal([]byte(result.(string)), &actionsResp)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling actions response: %v", err)
+	}
+
+	return &actionsResp, nil
+}
+
+// ExtractTags extracts tags, named entities and a category. If taxonomy is
+// not empty, the category is picked from it, and is empty if the model
+// answered with a category outside of it.
+func (e *AIEditor) ExtractTags(ctx context.Context, transcript string, taxonomy []string) (*TagsResponse, error) {
+	input := fmt.Sprintf("<transcription>\n%s\n</transcription>", transcript)
+	if len(taxonomy) > 0 {
+		input += fmt.Sprintf("\n<categories>\n- %s\n</categories>", strings.Join(taxonomy, "\n- "))
+	}
+
+	result, err := e.TagsAgent.Process(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error processing with tags agent: %w", err)
+	}
+
+	var tagsResp TagsResponse
+	err = json.Unmarshal([]byte(result.(string)), &tagsResp)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling tags response: %v", err)
+	}
+
+	if len(taxonomy) > 0 {
+		category := tagsResp.Category
+		tagsResp.Category = ""
+		for _, c := range taxonomy {
+			if strings.EqualFold(strings.TrimSpace(category), c) {
+				tagsResp.Category = c
+				break
+			}
+		}
+	}
+
+	return &tagsResp, nil
+}
+
+// Translate translates a cleaned transcription and its summary into language
+func (e *AIEditor) Translate(ctx context.Context, edited *EditorResponse, language string) (*EditorResponse, error) {
+	input := fmt.Sprintf("<transcription>\n%s\n</transcription>\n<summary>\n%s\n</summary>\n<target_language>%s</target_language>",
+		edited.CleanedTranscription, edited.Summary, language)
+
+	result, err := e.TranslateAgent.Process(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error processing with translate agent: %w", err)
+	}
+
+	var translateResp EditorResponse
+	err = json.Unmarshal([]byte(result.(string)), &translateResp)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling translate response: %v", err)
+	}
+
+	return &translateResp, nil
+}
+
+// Answer answers a question from excerpts of past recordings, citing them by number
+func (e *AIEditor) Answer(ctx context.Context, question string, excerpts []Excerpt) (*AnswerResponse, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<question>%s</question>\n<excerpts>\n", question)
+	for i, ex := range excerpts {
+		fmt.Fprintf(&b, "<excerpt number=\"%d\" source=\"%s\" date=\"%s\">\n%s\n</excerpt>\n", i+1, ex.Source, ex.Date, ex.Text)
+	}
+	b.WriteString("</excerpts>")
+
+	result, err := e.AnswerAgent.Process(ctx, b.String())
+	if err != nil {
+		return nil, fmt.Errorf("error processing with answer agent: %w", err)
+	}
+
+	var answerResp AnswerResponse
+	err = json.Unmarshal([]byte(result.(string)), &answerResp)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling answer response: %v", err)
+	}
+
+	return &answerResp, nil
+}
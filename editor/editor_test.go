@@ -1,11 +1,17 @@
 package editor
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/r4h4/article-helper/usage"
 )
 
 func TestAIEditor(t *testing.T) {
@@ -45,7 +51,99 @@ func TestAIEditor(t *testing.T) {
 					},
 				},
 			}
-		case contains(req.Messages[1].Content, "Based on the following summary, create a short, catchy headline"):
+		case contains(req.Messages[1].Content, "Based on the following summary, create ranked headline candidates"):
+			resp = OpenAIResponse{
+				Choices: []struct {
+					Message struct {
+						Content string `json:"content"`
+					} `json:"message"`
+				}{
+					{
+						Message: struct {
+							Content string `json:"content"`
+						}{
+							Content: `{"headlines": [{"style": "title", "text": "Test Transcript Summary"}, {"style": "title", "text": "Testing Transcripts"}, {"style": "email", "text": "Summary of the test transcript"}], "slug": "Test_Transcript_Summary"}`,
+						},
+					},
+				},
+				Usage: Usage{PromptTokens: 1000, CompletionTokens: 20, TotalTokens: 1020},
+			}
+		case contains(req.Messages[1].Content, "You will be given a transcription of a conversation between several people"):
+			resp = OpenAIResponse{
+				Choices: []struct {
+					Message struct {
+						Content string `json:"content"`
+					} `json:"message"`
+				}{
+					{
+						Message: struct {
+							Content string `json:"content"`
+						}{
+							Content: `{"segments": [{"speaker": "SPEAKER_1", "text": "How are you?"}, {"speaker": "SPEAKER_2", "text": "Fine, thanks."}]}`,
+						},
+					},
+				},
+			}
+		case contains(req.Messages[1].Content, "You will be given a transcription of a meeting or conversation."):
+			resp = OpenAIResponse{
+				Choices: []struct {
+					Message struct {
+						Content string `json:"content"`
+					} `json:"message"`
+				}{
+					{
+						Message: struct {
+							Content string `json:"content"`
+						}{
+							Content: `{"action_items": [{"owner": "Alice", "task": "Send the report", "due": "Friday"}], "decisions": [{"decision": "Ship in May"}], "open_questions": []}`,
+						},
+					},
+				},
+			}
+		case contains(req.Messages[1].Content, "You will be given a transcription and, optionally, a list of categories."):
+			if !slices.ContainsFunc([]string{"meeting\n- idea", "Meeting\n- Idea", "idea\n- note"}, func(taxonomy string) bool {
+				return strings.Contains(req.Messages[1].Content, "<categories>\n- "+taxonomy+"\n</categories>")
+			}) {
+				t.Errorf("Expected the taxonomy in the request, got %s", req.Messages[1].Content)
+			}
+			resp = OpenAIResponse{
+				Choices: []struct {
+					Message struct {
+						Content string `json:"content"`
+					} `json:"message"`
+				}{
+					{
+						Message: struct {
+							Content string `json:"content"`
+						}{
+							Content: `{"tags": ["pricing"], "entities": {"people": ["Alice"], "companies": ["Acme"], "products": []}, "category": "meeting"}`,
+						},
+					},
+				},
+			}
+		case contains(req.Messages[1].Content, "You will be given a cleaned-up transcription and its summary."):
+			if !strings.Contains(req.Messages[1].Content, "<target_language>german</target_language>") {
+				t.Errorf("Expected the target language in the request, got %s", req.Messages[1].Content)
+			}
+			resp = OpenAIResponse{
+				Choices: []struct {
+					Message struct {
+						Content string `json:"content"`
+					} `json:"message"`
+				}{
+					{
+						Message: struct {
+							Content string `json:"content"`
+						}{
+							Content: `{"cleaned_transcription": "Dies ist ein Test.", "summary": "- Ein Test"}`,
+						},
+					},
+				},
+			}
+		case contains(req.Messages[1].Content, "You will be given a question and numbered excerpts"):
+			if !strings.Contains(req.Messages[1].Content, `<excerpt number="2" source="20260305_101500_Pricing" date="2026-03-05 10:15">`) {
+				t.Errorf("Expected numbered excerpts in the request, got %s", req.Messages[1].Content)
+			}
 			resp = OpenAIResponse{
 				Choices: []struct {
 					Message struct {
@@ -56,7 +154,7 @@ func TestAIEditor(t *testing.T) {
 						Message: struct {
 							Content string `json:"content"`
 						}{
-							Content: `{"headline": "Test_Transcript_Summary"}`,
+							Content: `{"answer": "Prices go up next quarter [2].", "sources": [2]}`,
 						},
 					},
 				},
@@ -84,7 +182,7 @@ func TestAIEditor(t *testing.T) {
 	editor := NewAIEditor("test-api-key", testUrl)
 
 	t.Run("EditAndSummarize", func(t *testing.T) {
-		result, err := editor.EditAndSummarize("This is a test transcript.")
+		result, err := editor.EditAndSummarize(context.Background(), "This is a test transcript.", EditOptions{Language: "english"})
 		if err != nil {
 			t.Fatalf("EditAndSummarize failed: %v", err)
 		}
@@ -100,15 +198,132 @@ func TestAIEditor(t *testing.T) {
 		}
 	})
 
+	t.Run("TrackUsage", func(t *testing.T) {
+		tracker := usage.NewTracker(nil)
+		editor.TrackUsage(tracker)
+		defer editor.TrackUsage(nil)
+
+		if _, err := editor.CreateHeadline(context.Background(), "This is a summary of the test transcript.", 2); err != nil {
+			t.Fatalf("CreateHeadline failed: %v", err)
+		}
+
+		summary := tracker.Summary()
+		if len(summary.Models) != 1 {
+			t.Fatalf("Expected usage of one model, got %+v", summary.Models)
+		}
+		m := summary.Models[0]
+		if m.Model != "gpt-3.5-turbo" || m.Requests != 1 || m.PromptTokens != 1000 || m.CompletionTokens != 20 {
+			t.Errorf("Unexpected usage: %+v", m)
+		}
+		if m.Cost <= 0 {
+			t.Errorf("Expected a cost, got %f", m.Cost)
+		}
+	})
+
 	t.Run("CreateHeadline", func(t *testing.T) {
-		res, err := editor.CreateHeadline("This is a summary of the test transcript.")
+		res, err := editor.CreateHeadline(context.Background(), "This is a summary of the test transcript.", 2)
 		if err != nil {
 			t.Fatalf("CreateHeadline failed: %v", err)
 		}
 
-		expectedHeadline := "Test_Transcript_Summary"
-		if res.Headline != expectedHeadline {
-			t.Errorf("Expected headline %q, got %q", expectedHeadline, res.Headline)
+		if titles := res.Headlines.Of(HeadlineTitle); !reflect.DeepEqual(titles, []string{"Test Transcript Summary", "Testing Transcripts"}) {
+			t.Errorf("Unexpected titles %q", titles)
+		}
+		if email := res.Headlines.Best(HeadlineEmail); email != "Summary of the test transcript" {
+			t.Errorf("Unexpected email subject %q", email)
+		}
+		if res.Headlines.Best(HeadlineSocial) != "" {
+			t.Errorf("Expected no social post hook, got %q", res.Headlines.Best(HeadlineSocial))
+		}
+		// The slug is cleaned up
+		if res.Slug != "test-transcript-summary" {
+			t.Errorf("Expected slug %q, got %q", "test-transcript-summary", res.Slug)
+		}
+	})
+
+	t.Run("AttributeSpeakers", func(t *testing.T) {
+		res, err := editor.AttributeSpeakers(context.Background(), "How are you? Fine, thanks.")
+		if err != nil {
+			t.Fatalf("AttributeSpeakers failed: %v", err)
+		}
+
+		if len(res.Segments) != 2 {
+			t.Fatalf("Expected 2 segments, got %d", len(res.Segments))
+		}
+		if res.Segments[1].Speaker != "SPEAKER_2" || res.Segments[1].Text != "Fine, thanks." {
+			t.Errorf("Unexpected second segment: %+v", res.Segments[1])
+		}
+	})
+
+	t.Run("ExtractActions", func(t *testing.T) {
+		res, err := editor.ExtractActions(context.Background(), "Alice: I'll send the report by Friday. Bob: Let's ship in May.")
+		if err != nil {
+			t.Fatalf("ExtractActions failed: %v", err)
+		}
+
+		expectedItem := ActionItem{Owner: "Alice", Task: "Send the report", Due: "Friday"}
+		if len(res.ActionItems) != 1 || res.ActionItems[0] != expectedItem {
+			t.Errorf("Expected action items [%+v], got %+v", expectedItem, res.ActionItems)
+		}
+		if len(res.Decisions) != 1 || res.Decisions[0].Decision != "Ship in May" {
+			t.Errorf("Unexpected decisions: %+v", res.Decisions)
+		}
+		if len(res.OpenQuestions) != 0 {
+			t.Errorf("Expected no open questions, got %+v", res.OpenQuestions)
+		}
+	})
+
+	t.Run("ExtractTags", func(t *testing.T) {
+		res, err := editor.ExtractTags(context.Background(), "Alice from Acme asked about pricing.", []string{"meeting", "idea"})
+		if err != nil {
+			t.Fatalf("ExtractTags failed: %v", err)
+		}
+
+		if len(res.Tags) != 1 || res.Tags[0] != "pricing" {
+			t.Errorf("Unexpected tags: %v", res.Tags)
+		}
+		if len(res.Entities.People) != 1 || res.Entities.Companies[0] != "Acme" {
+			t.Errorf("Unexpected entities: %+v", res.Entities)
+		}
+		if res.Category != "meeting" {
+			t.Errorf("Expected category %q, got %q", "meeting", res.Category)
+		}
+
+		// The category is spelled as in the taxonomy
+		res, err = editor.ExtractTags(context.Background(), "Alice from Acme asked about pricing.", []string{"Meeting", "Idea"})
+		if err != nil || res.Category != "Meeting" {
+			t.Errorf("Expected category %q, got %q (%v)", "Meeting", res.Category, err)
+		}
+
+		// A category outside the taxonomy is dropped
+		res, err = editor.ExtractTags(context.Background(), "Alice from Acme asked about pricing.", []string{"idea", "note"})
+		if err != nil || res.Category != "" || len(res.Tags) != 1 {
+			t.Errorf("Expected no category, got %q with tags %v (%v)", res.Category, res.Tags, err)
+		}
+	})
+
+	t.Run("Translate", func(t *testing.T) {
+		res, err := editor.Translate(context.Background(), &EditorResponse{CleanedTranscription: "This is a test.", Summary: "- A test"}, "german")
+		if err != nil {
+			t.Fatalf("Translate failed: %v", err)
+		}
+
+		if res.CleanedTranscription != "Dies ist ein Test." || res.Summary != "- Ein Test" {
+			t.Errorf("Unexpected translation: %+v", res)
+		}
+	})
+
+	t.Run("Answer", func(t *testing.T) {
+		res, err := editor.Answer(context.Background(), "What did we decide about pricing?", []Excerpt{
+			{Source: "20260301_090000_Garden", Date: "2026-03-01 09:00", Text: "Plant tomatoes."},
+			{Source: "20260305_101500_Pricing", Date: "2026-03-05 10:15", Text: "We raise prices next quarter."},
+		})
+		if err != nil {
+			t.Fatalf("Answer failed: %v", err)
+		}
+
+		if res.Answer != "Prices go up next quarter [2]." || len(res.Sources) != 1 || res.Sources[0] != 2 {
+			t.Errorf("Unexpected answer: %+v", res)
 		}
 	})
 }
@@ -116,3 +331,20 @@ func TestAIEditor(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[:len(substr)] == substr
 }
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Enterprise Pricing Update": "enterprise-pricing-update",
+		"  Q3: Roadmap & Hiring!? ": "q3-roadmap-hiring",
+		"already-a-slug":            "already-a-slug",
+		"Müller über Preise":        "müller-über-preise",
+		"../etc/passwd":             "etc-passwd",
+		"":                          "",
+		strings.Repeat("word ", 20): strings.TrimSuffix(strings.Repeat("word-", 12), "-"),
+	}
+	for text, want := range tests {
+		if got := Slugify(text); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -2,37 +2,164 @@ package editor
 
 const (
 	EditorPrompt = `You will be given a transcription of someone's speech. Your task is to clean it up, summarize it, and output the result as a JSON.
-1. First, read the following transcription:
-<transcription>
+1. First, read the following transcription, which may be followed by additional context:
 %s
-</transcription>
 
 2. Clean up the transcription:
    - Remove filler words (um, uh, like, you know, etc.)
    - Correct any obvious grammatical errors
+   - If a <glossary> is given, use its spelling for names and terms, including words that sound like them or match their listed misspellings
    - Ensure proper capitalization and punctuation
    - Combine fragmented sentences into coherent thoughts
    - Remove any irrelevant or repetitive content
+   - If lines start with a speaker name followed by a colon, keep one line per speaker turn with the speaker name in front
 
 3. Summarize the cleaned-up transcription in bullet points:
    - Identify the main ideas and key points
    - Create concise bullet points that capture the essence of each main idea
    - Ensure the summary is comprehensive yet brief
+   - If the transcription has several speakers, attribute key points to the speaker who made them
+
+4. Keep the language of the transcription:
+   - If a <language> is given, the transcription is in that language
+   - Write both the cleaned-up transcription and the summary in the language of the transcription, do not translate them
+
+5. If <instructions> are given, follow them when cleaning up and summarizing, e.g. to make the summary shorter or more formal
 
-5. Output your results as a JSON with two keys "cleaned_transcription" (string), and "summary" (string). Example:
+6. Output your results as a JSON with two keys "cleaned_transcription" (string), and "summary" (string). Example:
    {
 	   "cleaned_transcription": "Insert the cleaned-up transcription here",
 	   "summary": "Insert bullet point summary here"
    }
 `
-	HeadlinePrompt = `Based on the following summary, create a short, catchy headline with maximum 5 words that could be used as a directory name.
-	Please provide the headline as a JSON with a single key "headline" (string). Example:
-	{
-		"headline": "Insert your headline here"
-	}
-
-	<summary>
-	%s
-	</summary>
-	`
+	SpeakerPrompt = `You will be given a transcription of a conversation between several people, without any indication of who is speaking. Your task is to split it into speaker turns and attribute each turn to a speaker.
+1. Read the following transcription:
+<transcription>
+%s
+</transcription>
+
+2. Split the transcription into turns wherever the speaker changes. Use cues such as questions and answers, forms of address, changes in perspective and topic.
+
+3. Label the speakers "SPEAKER_1", "SPEAKER_2" and so on, in the order they first speak. Use the same label for the same person throughout. Do not change, clean up or omit any of the text.
+
+4. Output your result as a JSON with a single key "segments", a list of objects with the keys "speaker" (string) and "text" (string). Example:
+   {
+	   "segments": [
+		   {"speaker": "SPEAKER_1", "text": "Insert the first turn here"},
+		   {"speaker": "SPEAKER_2", "text": "Insert the second turn here"}
+	   ]
+   }
+`
+	ActionsPrompt = `You will be given a transcription of a meeting or conversation. Your task is to extract the action items, decisions and open questions from it and output them as a JSON.
+1. Read the following transcription:
+<transcription>
+%s
+</transcription>
+
+2. Extract the action items:
+   - Include every task someone committed to or was asked to do
+   - Set "owner" to the person responsible, as named in the transcription. Leave it empty if nobody was named
+   - Set "due" to the due date or deadline exactly as it was spoken (e.g. "next Friday"). Leave it empty if none was mentioned
+   - Phrase each task as a short imperative sentence
+
+3. Extract the decisions that were agreed on, each as a short sentence.
+
+4. Extract the questions that were raised but not answered. Set "owner" to the person expected to answer, if one was named.
+
+5. Output your results as a JSON with three keys "action_items", "decisions" and "open_questions". Use empty lists if there is nothing to extract. Do not invent anything that was not said. Example:
+   {
+	   "action_items": [
+		   {"owner": "Insert owner here", "task": "Insert task here", "due": "Insert due date here"}
+	   ],
+	   "decisions": [
+		   {"decision": "Insert decision here"}
+	   ],
+	   "open_questions": [
+		   {"question": "Insert question here", "owner": "Insert owner here"}
+	   ]
+   }
+`
+	TagsPrompt = `You will be given a transcription and, optionally, a list of categories. Your task is to extract keywords and named entities from the transcription so it can be filed and searched later, and output them as a JSON.
+1. Read the following input:
+%s
+
+2. Extract up to 10 tags:
+   - Use short, lowercase keywords or key phrases describing the topics discussed
+   - Prefer general terms that are likely to be shared with other recordings
+
+3. Extract the named entities mentioned in the transcription:
+   - "people": names of people
+   - "companies": names of companies and organizations
+   - "products": names of products, services and projects
+
+4. Pick the category that fits the transcription best:
+   - If a list of categories is given, use exactly one of them
+   - Otherwise, use a single general category such as "meeting", "idea" or "note"
+
+5. Output your results as a JSON with the keys "tags" (list of strings), "entities" (object with the keys "people", "companies" and "products", each a list of strings) and "category" (string). Example:
+   {
+	   "tags": ["insert tag here"],
+	   "entities": {
+		   "people": ["Insert name here"],
+		   "companies": ["Insert company here"],
+		   "products": ["Insert product here"]
+	   },
+	   "category": "Insert category here"
+   }
+`
+	TranslatePrompt = `You will be given a cleaned-up transcription and its summary. Your task is to translate both into the target language and output the result as a JSON.
+1. Read the following input:
+%s
+
+2. Translate the transcription and the summary into the language given in <target_language>:
+   - Preserve the meaning, tone and structure, including bullet points and speaker names
+   - Do not add, remove or summarize anything
+
+3. Output your results as a JSON with two keys "cleaned_transcription" (string), and "summary" (string). Example:
+   {
+	   "cleaned_transcription": "Insert the translated transcription here",
+	   "summary": "Insert the translated summary here"
+   }
+`
+	AnswerPrompt = `You will be given a question and numbered excerpts from transcripts of past recordings. Your task is to answer the question from the excerpts and output the result as a JSON.
+1. Read the following input:
+%s
+
+2. Answer the question given in <question>:
+   - Only use information from the excerpts, do not make anything up
+   - Cite the excerpts you use by their number in square brackets, e.g. [2]
+   - Mention the date when it matters, e.g. when excerpts contradict each other
+   - If the excerpts do not answer the question, say so
+   - Answer in the language of the question
+
+3. Output your results as a JSON with two keys "answer" (string) and "sources" (list of the numbers of the excerpts you cited). Example:
+   {
+	   "answer": "Insert your answer here [1]",
+	   "sources": [1]
+   }
+`
+	HeadlinePrompt = `Based on the following summary, create ranked headline candidates for the recording in several styles.
+1. Read the following summary, followed by the number of candidates to create per style:
+%s
+
+2. Create that many candidates in each of these styles, best first:
+   - "title": a short, catchy headline with maximum 5 words
+   - "seo": a descriptive title for search engines with maximum 60 characters, naming the main topic
+   - "social": a hook for a social media post, one sentence that makes people want to read more
+   - "email": a clear subject line for an email sharing the recording
+
+3. Create a slug for the folder name of the recording from the best title: lowercase ASCII words joined by hyphens, e.g. "enterprise-pricing-update"
+
+4. Write the headlines in the language of the summary
+
+5. Output your results as a JSON with two keys "headlines" (list of objects with the keys "style" and "text") and "slug" (string). Example:
+   {
+	   "headlines": [
+		   {"style": "title", "text": "Insert the best title here"},
+		   {"style": "title", "text": "Insert the second best title here"},
+		   {"style": "seo", "text": "Insert the best SEO title here"}
+	   ],
+	   "slug": "insert-the-slug-here"
+   }
+`
 )
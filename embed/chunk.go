@@ -0,0 +1,65 @@
+package embed
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	chunkWords   = 150 // Target length of a chunk
+	overlapWords = 30  // Words repeated from the end of the previous chunk
+)
+
+// Split cuts text into chunks of about chunkWords words along sentence
+// boundaries. Consecutive chunks overlap, so a statement spanning a boundary
+// is still found.
+func Split(text string) []string {
+	sentences := splitSentences(text)
+
+	var chunks []string
+	var current []string
+	words := 0
+	for _, s := range sentences {
+		n := len(strings.Fields(s))
+		if words > 0 && words+n > chunkWords {
+			chunks = append(chunks, strings.Join(current, " "))
+
+			// Carry the last sentences over, up to overlapWords
+			keep, kept := len(current), 0
+			for keep > 0 && kept+len(strings.Fields(current[keep-1])) <= overlapWords {
+				keep--
+				kept += len(strings.Fields(current[keep]))
+			}
+			current, words = append([]string(nil), current[keep:]...), kept
+		}
+		current = append(current, s)
+		words += n
+	}
+	if words > 0 {
+		chunks = append(chunks, strings.Join(current, " "))
+	}
+	return chunks
+}
+
+// splitSentences splits text after sentence-ending punctuation and line breaks
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		end := r == '\n'
+		if r == '.' || r == '!' || r == '?' {
+			end = i+1 == len(runes) || unicode.IsSpace(runes[i+1])
+		}
+		if end {
+			if s := strings.Join(strings.Fields(string(runes[start:i+1])), " "); s != "" {
+				sentences = append(sentences, s)
+			}
+			start = i + 1
+		}
+	}
+	if s := strings.Join(strings.Fields(string(runes[start:])), " "); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
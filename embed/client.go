@@ -0,0 +1,177 @@
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/r4h4/article-helper/usage"
+)
+
+const (
+	defaultEndpoint = "https://api.openai.com/v1/embeddings"
+	defaultModel    = "text-embedding-3-small"
+
+	// Inputs per request, well below the API limit of 2048
+	batchSize = 64
+)
+
+// Config holds the configuration for the embeddings client. Any server
+// implementing the OpenAI embeddings API can be used, e.g. a local Ollama at
+// http://localhost:11434/v1/embeddings.
+type Config struct {
+	APIEndpoint string
+	APIKey      string // Optional for local servers
+	Model       string
+
+	// Tracker records the tokens used, if not nil
+	Tracker *usage.Tracker
+
+	// Transport sends the requests, default http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Client is an embeddings API client
+type Client struct {
+	config Config
+	client *http.Client
+}
+
+// NewClient creates a new embeddings API client
+func NewClient(config Config) *Client {
+	if config.APIEndpoint == "" {
+		config.APIEndpoint = defaultEndpoint
+	}
+	if config.Model == "" {
+		config.Model = defaultModel
+	}
+
+	return &Client{
+		config: config,
+		client: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: config.Transport,
+		},
+	}
+}
+
+// Model returns the name of the embedding model
+func (c *Client) Model() string {
+	return c.config.Model
+}
+
+// WithTransport returns a copy of the client sending its requests through rt
+func (c *Client) WithTransport(rt http.RoundTripper) *Client {
+	copied := *c
+	copied.config.Transport = rt
+	copied.client = &http.Client{Timeout: c.client.Timeout, Transport: rt}
+	return &copied
+}
+
+// WithTracker returns a copy of the client recording its usage in t
+func (c *Client) WithTracker(t *usage.Tracker) *Client {
+	copied := *c
+	copied.config.Tracker = t
+	return &copied
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+	} `json:"usage"`
+}
+
+// Embed returns one vector per text, in the same order
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		batch := texts[start:min(start+batchSize, len(texts))]
+		result, err := c.embedBatch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, result...)
+	}
+	return vectors, nil
+}
+
+func (c *Client) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: c.config.Model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request body: %w", err)
+	}
+
+	var vectors [][]float32
+	operation := func() error {
+		var err error
+		vectors, err = c.send(ctx, body, len(texts))
+		return err
+	}
+
+	err = backoff.Retry(operation, backoff.WithContext(backoff.NewExponentialBackOff(), ctx))
+	if err != nil {
+		return nil, fmt.Errorf("embedding failed after retries: %w", err)
+	}
+	return vectors, nil
+}
+
+func (c *Client) send(ctx context.Context, body []byte, count int) ([][]float32, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.APIEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, backoff.Permanent(fmt.Errorf("error creating request: %w", err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("API request failed with status code %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+		// Retrying will not fix the request itself
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, backoff.Permanent(err)
+		}
+		return nil, err
+	}
+
+	var result embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	if len(result.Data) != count {
+		return nil, backoff.Permanent(fmt.Errorf("expected %d embeddings, got %d", count, len(result.Data)))
+	}
+
+	c.config.Tracker.AddTokens(c.config.Model, result.Usage.PromptTokens, 0)
+
+	vectors := make([][]float32, count)
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= count {
+			return nil, backoff.Permanent(fmt.Errorf("embedding index %d out of range", d.Index))
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,106 @@
+package embed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// vocabulary of the fake embedding model, one dimension per word
+var vocabulary = []string{"pricing", "enterprise", "garden", "tomatoes", "weather"}
+
+func fakeEmbeddings(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Error decoding request: %v", err)
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		var resp embeddingResponse
+		resp.Data = make([]struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}, len(req.Input))
+		for i, text := range req.Input {
+			resp.Data[i].Index = i
+			resp.Data[i].Embedding = make([]float32, len(vocabulary))
+			for j, word := range vocabulary {
+				resp.Data[i].Embedding[j] = float32(strings.Count(strings.ToLower(text), word))
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestSplit(t *testing.T) {
+	sentence := "This sentence has exactly eight words in it. "
+	chunks := Split(strings.Repeat(sentence, 40))
+
+	if len(chunks) < 2 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := len(strings.Fields(c)); n > chunkWords {
+			t.Errorf("Chunk %d has %d words, expected at most %d", i, n, chunkWords)
+		}
+		if !strings.HasSuffix(c, ".") {
+			t.Errorf("Chunk %d does not end at a sentence boundary: %q", i, c)
+		}
+	}
+
+	if chunks := Split("  "); len(chunks) != 0 {
+		t.Errorf("Expected no chunks for empty text, got %v", chunks)
+	}
+}
+
+func TestStore(t *testing.T) {
+	server := fakeEmbeddings(t)
+	defer server.Close()
+
+	client := NewClient(Config{APIEndpoint: server.URL, Model: "fake"})
+	dir := t.TempDir()
+	store, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := store.Index(ctx, client, "pricing", "We discussed enterprise pricing."); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if err := store.Index(ctx, client, "garden", "The tomatoes in the garden need rain, the weather was dry."); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	// Reopen to make sure the vectors were persisted
+	if store, err = OpenStore(dir); err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	if !store.Has("garden") || store.Has("unknown") {
+		t.Error("Has does not reflect the indexed recordings")
+	}
+
+	query, err := client.Embed(ctx, []string{"what about tomatoes?"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	matches := store.Search(query[0], 1, nil)
+	if len(matches) != 1 || matches[0].Recording != "garden" {
+		t.Errorf("Expected the garden recording, got %+v", matches)
+	}
+
+	matches = store.Search(query[0], 5, func(recording string) bool { return recording != "garden" })
+	if len(matches) != 1 || matches[0].Recording != "pricing" {
+		t.Errorf("Expected the filter to exclude the garden recording, got %+v", matches)
+	}
+
+	other := NewClient(Config{APIEndpoint: server.URL, Model: "other"})
+	if err := store.Index(ctx, other, "pricing", "Pricing."); err == nil {
+		t.Error("Expected mixing embedding models to fail")
+	}
+}
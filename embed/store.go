@@ -0,0 +1,201 @@
+package embed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/r4h4/article-helper/internal/filelock"
+)
+
+// StoreFile is the name of the vector store in the library root
+const StoreFile = "vectors.json"
+
+// Chunk is an embedded part of a recording's transcript
+type Chunk struct {
+	Recording string    `json:"recording"` // Library entry ID
+	Index     int       `json:"index"`     // Position in the transcript
+	Text      string    `json:"text"`
+	Vector    []float32 `json:"vector"` // Normalized to unit length
+}
+
+// Match is a chunk found by Search
+type Match struct {
+	Chunk
+	Score float64 // Cosine similarity
+}
+
+// Store persists chunk vectors in a JSON file. All vectors must come from
+// the same model, which is recorded in the file.
+type Store struct {
+	path   string
+	mu     sync.Mutex
+	Model  string  `json:"model"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// OpenStore loads the vector store in dir. A missing file yields an empty store.
+func OpenStore(dir string) (*Store, error) {
+	s := &Store{path: filepath.Join(dir, StoreFile)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Has reports whether a recording has been embedded
+func (s *Store) Has(recording string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.Chunks {
+		if c.Recording == recording {
+			return true
+		}
+	}
+	return false
+}
+
+// Index splits the text of a recording into chunks, embeds them and saves
+// them, replacing earlier chunks of the recording
+func (s *Store) Index(ctx context.Context, client *Client, recording, text string) error {
+	texts := Split(text)
+	vectors, err := client.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	chunks := make([]Chunk, len(texts))
+	for i, t := range texts {
+		chunks[i] = Chunk{Recording: recording, Index: i, Text: t, Vector: normalize(vectors[i])}
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Pick up recordings embedded by other processes since Open
+	if err := s.load(); err != nil {
+		return err
+	}
+	if s.Model != "" && s.Model != client.Model() {
+		return fmt.Errorf("vector store holds embeddings of model %s, not %s; run the embed command with -all to rebuild it", s.Model, client.Model())
+	}
+	s.Model = client.Model()
+
+	kept := s.Chunks[:0]
+	for _, c := range s.Chunks {
+		if c.Recording != recording {
+			kept = append(kept, c)
+		}
+	}
+	s.Chunks = append(kept, chunks...)
+	return s.save()
+}
+
+// Reset removes all chunks, e.g. before switching to another model
+func (s *Store) Reset() error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Model = ""
+	s.Chunks = nil
+	return s.save()
+}
+
+// Search returns the k chunks most similar to the query vector, best first.
+// If keep is not nil, only chunks of recordings it accepts are considered.
+func (s *Store) Search(query []float32, k int, keep func(recording string) bool) []Match {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query = normalize(query)
+	var matches []Match
+	for _, c := range s.Chunks {
+		if len(c.Vector) != len(query) || (keep != nil && !keep(c.Recording)) {
+			continue
+		}
+		matches = append(matches, Match{Chunk: c, Score: dot(query, c.Vector)})
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// lock serializes writes of the store by all stores, also in other
+// processes, e.g. of parallel pipeline runs
+func (s *Store) lock() (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, fmt.Errorf("creating vector store directory: %w", err)
+	}
+	return filelock.Lock(s.path)
+}
+
+func (s *Store) load() error {
+	s.Model, s.Chunks = "", nil
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("reading vector store: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("parsing vector store: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encoding vector store: %w", err)
+	}
+	if err := os.WriteFile(s.path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("writing vector store: %w", err)
+	}
+	return os.Rename(s.path+".tmp", s.path)
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
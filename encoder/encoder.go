@@ -0,0 +1,93 @@
+package encoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Format is an audio format recordings can be encoded to before upload
+type Format string
+
+const (
+	WAV  Format = "wav"  // Lossless master, uploaded as is
+	FLAC Format = "flac" // Lossless, encoded in pure Go
+	OGG  Format = "ogg"  // Opus in an Ogg container, requires ffmpeg
+	WebM Format = "webm" // Opus in a WebM container, requires ffmpeg
+	MP3  Format = "mp3"  // Requires ffmpeg or sox built with LAME
+)
+
+// Formats lists all supported formats
+var Formats = []Format{WAV, FLAC, OGG, WebM, MP3}
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported audio format: %s", s)
+}
+
+// Encode converts the WAV file at src to format, writing the result next to it
+// with the matching extension. The source file is kept. It returns the path of
+// the encoded file, which is src itself for WAV.
+func Encode(ctx context.Context, src string, format Format) (string, error) {
+	if format == WAV {
+		return src, nil
+	}
+
+	dst := strings.TrimSuffix(src, filepath.Ext(src)) + "." + string(format)
+
+	switch {
+	case format == FLAC:
+		return dst, encodeFLAC(src, dst)
+	case hasCommand("ffmpeg"):
+		return dst, runFFmpeg(ctx, src, dst, format)
+	// sox picks Vorbis for .ogg, so it only encodes MP3
+	case hasCommand("sox") && format == MP3:
+		return dst, runSox(ctx, src, dst)
+	case format == MP3:
+		return "", fmt.Errorf("encoding to %s requires ffmpeg or sox", format)
+	default:
+		return "", fmt.Errorf("encoding to %s requires ffmpeg", format)
+	}
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// Speech-tuned settings; Whisper resamples to 16 kHz mono anyway
+var ffmpegCodecs = map[Format][]string{
+	OGG:  {"-c:a", "libopus", "-b:a", "32k", "-application", "voip"},
+	WebM: {"-c:a", "libopus", "-b:a", "32k", "-application", "voip"},
+	MP3:  {"-c:a", "libmp3lame", "-b:a", "64k"},
+}
+
+func runFFmpeg(ctx context.Context, src, dst string, format Format) error {
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", src}, ffmpegCodecs[format]...)
+	return runEncoder(exec.CommandContext(ctx, "ffmpeg", append(args, dst)...))
+}
+
+// runSox lets sox pick the encoder from the destination extension, i.e. LAME for .mp3
+func runSox(ctx context.Context, src, dst string) error {
+	return runEncoder(exec.CommandContext(ctx, "sox", "-q", src, dst))
+}
+
+func runEncoder(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %w: %s", filepath.Base(cmd.Path), err, msg)
+		}
+		return fmt.Errorf("%s: %w", filepath.Base(cmd.Path), err)
+	}
+	return nil
+}
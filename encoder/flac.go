@@ -0,0 +1,155 @@
+package encoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+
+	"github.com/go-audio/wav"
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+const (
+	flacBlockSize = 4096
+	// Largest Rice parameter of the 4-bit coding method; 15 is the escape code
+	maxRiceParam = 14
+)
+
+// encodeFLAC converts a 16-bit PCM WAV file to FLAC, using a second order fixed
+// predictor per block, which captures most of the redundancy in speech. The
+// samples are read one block at a time, so long recordings are not held in memory.
+func encodeFLAC(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dec := wav.NewDecoder(in)
+	if err := dec.FwdToPCM(); err != nil {
+		return fmt.Errorf("decoding %s: %w", src, err)
+	}
+	if dec.PCMChunk == nil {
+		return fmt.Errorf("decoding %s: %w", src, wav.ErrPCMChunkNotFound)
+	}
+	if dec.BitDepth != 16 {
+		return fmt.Errorf("unsupported bit depth: %d", dec.BitDepth)
+	}
+
+	channels := int(dec.NumChans)
+	frameSize := 2 * channels
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	info := &meta.StreamInfo{
+		BlockSizeMin:  flacBlockSize,
+		BlockSizeMax:  flacBlockSize,
+		SampleRate:    dec.SampleRate,
+		NChannels:     uint8(channels),
+		BitsPerSample: 16,
+		NSamples:      uint64(dec.PCMSize / frameSize),
+	}
+	enc, err := flac.NewEncoder(out, info)
+	if err != nil {
+		return fmt.Errorf("creating FLAC encoder: %w", err)
+	}
+
+	raw := make([]byte, flacBlockSize*frameSize)
+	for {
+		read, readErr := io.ReadFull(dec.PCMChunk.R, raw)
+		// A trailing partial frame is dropped
+		if n := read / frameSize; n > 0 {
+			subframes := make([]*frame.Subframe, channels)
+			for c := range subframes {
+				samples := make([]int32, n)
+				for i := range samples {
+					samples[i] = int32(int16(binary.LittleEndian.Uint16(raw[i*frameSize+2*c:])))
+				}
+				subframes[c] = newSubframe(samples)
+			}
+
+			f := &frame.Frame{
+				Header: frame.Header{
+					HasFixedBlockSize: true,
+					BlockSize:         uint16(n),
+					SampleRate:        dec.SampleRate,
+					Channels:          frame.Channels(channels - 1), // Independent channels
+					BitsPerSample:     16,
+				},
+				Subframes: subframes,
+			}
+			if err := enc.WriteFrame(f); err != nil {
+				return fmt.Errorf("encoding FLAC frame: %w", err)
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		} else if readErr != nil {
+			return fmt.Errorf("decoding %s: %w", src, readErr)
+		}
+	}
+
+	// Close rewrites the StreamInfo block, with the number of samples encoded, and closes out
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("finalizing FLAC stream: %w", err)
+	}
+	return nil
+}
+
+// newSubframe picks the prediction method for a block of samples
+func newSubframe(samples []int32) *frame.Subframe {
+	subframe := &frame.Subframe{
+		Samples:  samples,
+		NSamples: len(samples),
+	}
+
+	switch {
+	case isConstant(samples):
+		subframe.Pred = frame.PredConstant
+	case len(samples) <= 2:
+		subframe.Pred = frame.PredVerbatim
+	default:
+		subframe.Pred = frame.PredFixed
+		subframe.Order = 2
+		subframe.ResidualCodingMethod = frame.ResidualCodingMethodRice1
+		subframe.RiceSubframe = &frame.RiceSubframe{
+			PartOrder:  0,
+			Partitions: []frame.RicePartition{{Param: riceParam(samples)}},
+		}
+	}
+	return subframe
+}
+
+func isConstant(samples []int32) bool {
+	for _, s := range samples[1:] {
+		if s != samples[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// riceParam estimates the optimal Rice parameter from the mean magnitude of the
+// second order residuals
+func riceParam(samples []int32) uint {
+	var sum uint64
+	for i := 2; i < len(samples); i++ {
+		r := samples[i] - 2*samples[i-1] + samples[i-2]
+		sum += uint64(uint32(r<<1) ^ uint32(r>>31)) // ZigZag
+	}
+
+	mean := sum / uint64(len(samples)-2)
+	if mean == 0 {
+		return 0
+	}
+	return min(uint(bits.Len64(mean)-1), maxRiceParam)
+}
@@ -0,0 +1,139 @@
+package encoder
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+	"github.com/mewkiz/flac"
+)
+
+// writeTestWAV writes interleaved 16-bit samples with the given number of channels
+func writeTestWAV(t *testing.T, path string, channels int, samples []int) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Error creating WAV: %v", err)
+	}
+	defer f.Close()
+
+	enc := wav.NewEncoder(f, 16000, 16, channels, 1)
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: channels, SampleRate: 16000},
+		Data:           samples,
+		SourceBitDepth: 16,
+	}
+	if err := enc.Write(buf); err != nil {
+		t.Fatalf("Error writing WAV: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Error closing WAV: %v", err)
+	}
+}
+
+func TestEncodeFLAC(t *testing.T) {
+	// One second of silence followed by a decaying tone, with a partial last block
+	samples := make([]int, 16000+12345)
+	for i := 16000; i < len(samples); i++ {
+		decay := math.Exp(-float64(i-16000) / 8000)
+		samples[i] = int(20000 * decay * math.Sin(2*math.Pi*220*float64(i)/16000))
+	}
+
+	src := filepath.Join(t.TempDir(), "test.wav")
+	writeTestWAV(t, src, 1, samples)
+
+	dst, err := Encode(context.Background(), src, FLAC)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if filepath.Ext(dst) != ".flac" {
+		t.Errorf("Expected .flac output, got %s", dst)
+	}
+
+	wavInfo, _ := os.Stat(src)
+	flacInfo, _ := os.Stat(dst)
+	if flacInfo.Size() >= wavInfo.Size()/2 {
+		t.Errorf("Expected FLAC to be less than half the WAV size, got %d vs %d bytes", flacInfo.Size(), wavInfo.Size())
+	}
+
+	stream, err := flac.Open(dst)
+	if err != nil {
+		t.Fatalf("Error opening FLAC: %v", err)
+	}
+	defer stream.Close()
+
+	var decoded []int
+	for {
+		f, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Error decoding FLAC frame: %v", err)
+		}
+		for _, s := range f.Subframes[0].Samples {
+			decoded = append(decoded, int(s))
+		}
+	}
+
+	if len(decoded) != len(samples) {
+		t.Fatalf("Expected %d samples, got %d", len(samples), len(decoded))
+	}
+	for i := range samples {
+		if decoded[i] != samples[i] {
+			t.Fatalf("Sample %d differs: expected %d, got %d", i, samples[i], decoded[i])
+		}
+	}
+}
+
+func TestEncodeFLACStereo(t *testing.T) {
+	// Opposite ramps on the channels, over more than one block
+	frames := flacBlockSize + 100
+	samples := make([]int, 2*frames)
+	for i := range frames {
+		samples[2*i] = i % 30000
+		samples[2*i+1] = -(i % 30000)
+	}
+
+	src := filepath.Join(t.TempDir(), "stereo.wav")
+	writeTestWAV(t, src, 2, samples)
+	dst, err := Encode(context.Background(), src, FLAC)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	stream, err := flac.Open(dst)
+	if err != nil {
+		t.Fatalf("Error opening FLAC: %v", err)
+	}
+	defer stream.Close()
+	if stream.Info.NChannels != 2 || stream.Info.NSamples != uint64(frames) {
+		t.Errorf("Expected 2 channels of %d samples, got %d of %d", frames, stream.Info.NChannels, stream.Info.NSamples)
+	}
+
+	var decoded []int
+	for {
+		f, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Error decoding FLAC frame: %v", err)
+		}
+		for i := range f.Subframes[0].Samples {
+			decoded = append(decoded, int(f.Subframes[0].Samples[i]), int(f.Subframes[1].Samples[i]))
+		}
+	}
+	if len(decoded) != len(samples) {
+		t.Fatalf("Expected %d samples, got %d", len(samples), len(decoded))
+	}
+	for i := range samples {
+		if decoded[i] != samples[i] {
+			t.Fatalf("Sample %d differs: expected %d, got %d", i, samples[i], decoded[i])
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/r4h4/article-helper/config"
+	"github.com/r4h4/article-helper/export"
+)
+
+const defaultNotionTokenEnv = "NOTION_TOKEN"
+
+// ExportStep sends the results to other apps once they are saved
+type ExportStep struct {
+	Exporters []export.Exporter
+}
+
+func (s *ExportStep) Name() string      { return "export" }
+func (s *ExportStep) Requires() []Field { return []Field{FieldCleaned, FieldSummary} }
+func (s *ExportStep) Produces() []Field { return nil }
+
+// Execute runs every exporter, even if an earlier one fails
+func (s *ExportStep) Execute(ctx context.Context, state *State) error {
+	rec := &export.Recording{
+		ID:         filepath.Base(state.OutFolder),
+		Folder:     state.OutFolder,
+		Metadata:   state.Metadata(),
+		Summary:    state.Summary,
+		Transcript: state.CleanedTranscription,
+		Actions:    state.Actions,
+	}
+	if abs, err := filepath.Abs(rec.Folder); err == nil {
+		rec.Folder = abs
+	}
+
+	var errs []error
+	for _, exporter := range s.Exporters {
+		if err := exporter.Export(ctx, rec); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", exporter.Name(), err))
+			continue
+		}
+		slog.Info("Exported recording", "recording", state.Timestamp, "exporter", exporter.Name())
+	}
+	return errors.Join(errs...)
+}
+
+// newExporter returns the exporter configured by e. Notion requests are sent
+// through rt, if not nil. Webhook requests are not, as their configured
+// headers and URLs may carry secrets that rt could log.
+func newExporter(e config.Export, rt http.RoundTripper) (export.Exporter, error) {
+	switch e.Type {
+	case "obsidian":
+		if e.Vault == "" {
+			return nil, errors.New("obsidian export: no vault")
+		}
+		return export.NewObsidian(export.ObsidianConfig{Vault: e.Vault, Folder: e.Folder}), nil
+	case "notion":
+		tokenEnv := e.TokenEnv
+		if tokenEnv == "" {
+			tokenEnv = defaultNotionTokenEnv
+		}
+		token := os.Getenv(tokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("notion export: %s environment variable is not set", tokenEnv)
+		}
+		notion, err := export.NewNotion(export.NotionConfig{
+			Token:         token,
+			DatabaseID:    e.DatabaseID,
+			TitleProperty: e.TitleProperty,
+			PageID:        e.PageID,
+			Transport:     rt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("notion export: %w", err)
+		}
+		return notion, nil
+	case "webhook":
+		if e.URL == "" {
+			return nil, errors.New("webhook export: no URL")
+		}
+		header := make(http.Header)
+		for key, value := range e.Headers {
+			header.Set(key, os.ExpandEnv(value))
+		}
+		return export.NewWebhook(export.WebhookConfig{URL: e.URL, Header: header}), nil
+	}
+	return nil, fmt.Errorf("unknown export type %q, expected obsidian, notion or webhook", e.Type)
+}
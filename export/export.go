@@ -0,0 +1,43 @@
+// Package export sends processed recordings to note-taking apps and other services
+package export
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/recording"
+)
+
+// Timeout of each request to an export service
+const requestTimeout = 60 * time.Second
+
+// Exporter sends a processed recording somewhere outside the recordings folder
+type Exporter interface {
+	// Name identifies the exporter in logs and errors, e.g. "notion"
+	Name() string
+	Export(ctx context.Context, rec *Recording) error
+}
+
+// Recording is a processed recording as it is exported
+type Recording struct {
+	ID         string                  `json:"id"` // Name of the recording folder
+	Folder     string                  `json:"-"`  // Path of the recording folder
+	Metadata   *recording.Metadata     `json:"metadata"`
+	Summary    string                  `json:"summary"`
+	Transcript string                  `json:"transcript"` // Cleaned transcript
+	Actions    *editor.ActionsResponse `json:"actions,omitempty"`
+}
+
+// Title returns the headline, or the timestamp if there is none
+func (r *Recording) Title() string {
+	if r.Metadata.Headline != "" {
+		return r.Metadata.Headline
+	}
+	return "Recording " + r.Metadata.Timestamp
+}
+
+func newHTTPClient(rt http.RoundTripper) *http.Client {
+	return &http.Client{Timeout: requestTimeout, Transport: rt}
+}
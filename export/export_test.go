@@ -0,0 +1,181 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/recording"
+)
+
+func testRecording() *Recording {
+	return &Recording{
+		ID:     "20261019_140305_enterprise-pricing-update",
+		Folder: "/recordings/20261019_140305_enterprise-pricing-update",
+		Metadata: &recording.Metadata{
+			Timestamp: "20261019_140305",
+			Date:      time.Date(2026, 10, 19, 14, 3, 5, 0, time.UTC),
+			Headline:  "Enterprise Pricing: Update",
+			Slug:      "enterprise-pricing-update",
+			Tags:      []string{"pricing", "enterprise sales"},
+			Entities:  &editor.Entities{People: []string{"Alice"}, Companies: []string{"Acme"}},
+			Category:  "meeting",
+		},
+		Summary:    "- Enterprise pricing rises by 10%\n- Existing customers keep their price",
+		Transcript: "We decided to raise the enterprise pricing.",
+		Actions: &editor.ActionsResponse{
+			ActionItems: []editor.ActionItem{{Owner: "Alice", Task: "Email the customers", Due: "Friday"}},
+		},
+	}
+}
+
+// standIn records the requests to a stand-in for an HTTP API
+type standIn struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []map[string]any
+}
+
+func (s *standIn) serve(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Error decoding request: %v", err)
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+		respond(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestObsidian(t *testing.T) {
+	vault := t.TempDir()
+	o := NewObsidian(ObsidianConfig{Vault: vault, Folder: "Meetings"})
+	if err := o.Export(context.Background(), testRecording()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// The colon is not allowed in note names
+	data, err := os.ReadFile(filepath.Join(vault, "Meetings", "2026-10-19 Enterprise Pricing Update.md"))
+	if err != nil {
+		t.Fatalf("Reading note: %v", err)
+	}
+	note := string(data)
+	for _, expected := range []string{
+		"date: 2026-10-19\n",
+		"  - \"enterprise-sales\"\n",
+		"# Enterprise Pricing: Update\n",
+		"**People:** [[Alice]]\n",
+		"**Companies:** [[Acme]]\n",
+		"- [ ] [[Alice]]: Email the customers (due Friday)\n",
+	} {
+		if !strings.Contains(note, expected) {
+			t.Errorf("Expected the note to contain %q:\n%s", expected, note)
+		}
+	}
+
+	missing := NewObsidian(ObsidianConfig{Vault: filepath.Join(vault, "missing")})
+	if err := missing.Export(context.Background(), testRecording()); err == nil {
+		t.Error("Expected an error for a missing vault")
+	}
+}
+
+func TestNotion(t *testing.T) {
+	var api standIn
+	server := api.serve(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pages" {
+			w.Write([]byte(`{"object": "page", "id": "page-1"}`))
+		} else {
+			w.Write([]byte(`{"object": "list"}`))
+		}
+	})
+
+	notion, err := NewNotion(NotionConfig{Endpoint: server.URL, Token: "secret", DatabaseID: "db-1"})
+	if err != nil {
+		t.Fatalf("NewNotion failed: %v", err)
+	}
+	rec := testRecording()
+	rec.Transcript = strings.Repeat("A line of the transcript.\n", 150)
+	if err := notion.Export(context.Background(), rec); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// The blocks beyond the limit of a request are appended to the new page
+	if len(api.requests) != 2 || api.requests[1].Method != http.MethodPatch || api.requests[1].URL.Path != "/blocks/page-1/children" {
+		t.Fatalf("Expected the page to be created and appended to, got %d requests", len(api.requests))
+	}
+	if auth := api.requests[0].Header.Get("Authorization"); auth != "Bearer secret" || api.requests[0].Header.Get("Notion-Version") == "" {
+		t.Errorf("Unexpected headers %v", api.requests[0].Header)
+	}
+
+	page := api.bodies[0]
+	if parent := page["parent"].(map[string]any); parent["database_id"] != "db-1" {
+		t.Errorf("Unexpected parent %v", parent)
+	}
+	title := page["properties"].(map[string]any)["Name"].(map[string]any)["title"].([]any)[0].(map[string]any)
+	if title["text"].(map[string]any)["content"] != "Enterprise Pricing: Update" {
+		t.Errorf("Unexpected title %v", title)
+	}
+	children := page["children"].([]any)
+	if len(children) != maxNotionBlocks {
+		t.Errorf("Expected %d blocks in the first request, got %d", maxNotionBlocks, len(children))
+	}
+	if kind := children[1].(map[string]any)["type"]; kind != "bulleted_list_item" {
+		t.Errorf("Expected the summary as a list, got %v", kind)
+	}
+	if kind := children[4].(map[string]any)["type"]; kind != "to_do" {
+		t.Errorf("Expected the action item as a to-do, got %v", kind)
+	}
+	if n := len(api.bodies[1]["children"].([]any)); len(children)+n != 156 {
+		t.Errorf("Expected 156 blocks in total, got %d", len(children)+n)
+	}
+
+	if _, err := NewNotion(NotionConfig{Token: "secret"}); err == nil {
+		t.Error("Expected an error without a parent")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var api standIn
+	var status atomic.Int32
+	status.Store(http.StatusNoContent)
+	server := api.serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	})
+
+	webhook := NewWebhook(WebhookConfig{URL: server.URL + "/hook", Header: http.Header{"Authorization": {"Bearer secret"}}})
+	if err := webhook.Export(context.Background(), testRecording()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(api.requests) != 1 || api.requests[0].Method != http.MethodPost || api.requests[0].Header.Get("Authorization") != "Bearer secret" {
+		t.Fatalf("Expected an authorized POST, got %d requests", len(api.requests))
+	}
+	body := api.bodies[0]
+	if body["id"] != "20261019_140305_enterprise-pricing-update" || body["summary"] == "" {
+		t.Errorf("Unexpected body %v", body)
+	}
+	if _, ok := body["Folder"]; ok {
+		t.Error("Expected the local folder not to be sent")
+	}
+	if m := body["metadata"].(map[string]any); m["slug"] != "enterprise-pricing-update" {
+		t.Errorf("Unexpected metadata %v", m)
+	}
+
+	status.Store(http.StatusBadGateway)
+	if err := webhook.Export(context.Background(), testRecording()); err == nil || !strings.Contains(err.Error(), "status 502") {
+		t.Errorf("Expected a status error, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/r4h4/article-helper/internal/jsonapi"
+)
+
+const (
+	defaultNotionEndpoint = "https://api.notion.com/v1"
+	notionVersion         = "2022-06-28"
+	defaultTitleProperty  = "Name"
+
+	// Limits of the Notion API per request and per text object
+	maxNotionBlocks = 100
+	maxNotionText   = 2000
+)
+
+// NotionConfig holds the configuration for Notion exports. The pages are
+// created in a database, or below a page if no database is given.
+type NotionConfig struct {
+	Endpoint string // Default: https://api.notion.com/v1
+	Token    string // Of an integration with access to the parent
+
+	DatabaseID string
+	// TitleProperty is the title property of the database (default: Name)
+	TitleProperty string
+	PageID        string
+
+	// Transport sends the requests, default http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Notion creates a page per recording
+type Notion struct {
+	config NotionConfig
+	client *http.Client
+}
+
+// NewNotion creates a new Notion exporter
+func NewNotion(config NotionConfig) (*Notion, error) {
+	if config.Token == "" {
+		return nil, errors.New("no API token")
+	}
+	if (config.DatabaseID == "") == (config.PageID == "") {
+		return nil, errors.New("either a database or a page is needed as parent")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = defaultNotionEndpoint
+	}
+	if config.TitleProperty == "" {
+		config.TitleProperty = defaultTitleProperty
+	}
+	return &Notion{config: config, client: newHTTPClient(config.Transport)}, nil
+}
+
+func (n *Notion) Name() string { return "notion" }
+
+type notionBlock map[string]any
+
+// Export creates the page. Blocks beyond the limit per request are appended
+// to it afterwards.
+func (n *Notion) Export(ctx context.Context, rec *Recording) error {
+	blocks := notionBlocks(rec)
+	first := blocks[:min(maxNotionBlocks, len(blocks))]
+
+	page := map[string]any{"children": first}
+	if n.config.DatabaseID != "" {
+		page["parent"] = map[string]string{"database_id": n.config.DatabaseID}
+		page["properties"] = map[string]any{n.config.TitleProperty: map[string]any{"title": richText(rec.Title())}}
+	} else {
+		page["parent"] = map[string]string{"page_id": n.config.PageID}
+		page["properties"] = map[string]any{"title": map[string]any{"title": richText(rec.Title())}}
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := jsonapi.Send(ctx, n.client, http.MethodPost, n.config.Endpoint+"/pages", n.header(), page, &created); err != nil {
+		return fmt.Errorf("creating page: %w", err)
+	}
+
+	for start := len(first); start < len(blocks); start += maxNotionBlocks {
+		batch := blocks[start:min(start+maxNotionBlocks, len(blocks))]
+		url := fmt.Sprintf("%s/blocks/%s/children", n.config.Endpoint, created.ID)
+		if err := jsonapi.Send(ctx, n.client, http.MethodPatch, url, n.header(), map[string]any{"children": batch}, nil); err != nil {
+			return fmt.Errorf("appending to page %s: %w", created.ID, err)
+		}
+	}
+	return nil
+}
+
+func (n *Notion) header() http.Header {
+	return http.Header{
+		"Authorization":  {"Bearer " + n.config.Token},
+		"Notion-Version": {notionVersion},
+	}
+}
+
+func notionBlocks(rec *Recording) []notionBlock {
+	blocks := []notionBlock{textBlock("heading_2", "Summary")}
+	blocks = append(blocks, lineBlocks(rec.Summary)...)
+
+	if a := rec.Actions; a != nil {
+		if len(a.ActionItems) > 0 {
+			blocks = append(blocks, textBlock("heading_2", "Action Items"))
+			for _, item := range a.ActionItems {
+				line := item.Task
+				if item.Owner != "" {
+					line = fmt.Sprintf("%s: %s", item.Owner, line)
+				}
+				if item.Due != "" {
+					line += fmt.Sprintf(" (due %s)", item.Due)
+				}
+				todo := textBlock("to_do", line)
+				todo["to_do"].(map[string]any)["checked"] = false
+				blocks = append(blocks, todo)
+			}
+		}
+		if len(a.Decisions) > 0 {
+			blocks = append(blocks, textBlock("heading_2", "Decisions"))
+			for _, d := range a.Decisions {
+				blocks = append(blocks, textBlock("bulleted_list_item", d.Decision))
+			}
+		}
+		if len(a.OpenQuestions) > 0 {
+			blocks = append(blocks, textBlock("heading_2", "Open Questions"))
+			for _, q := range a.OpenQuestions {
+				blocks = append(blocks, textBlock("bulleted_list_item", q.Question))
+			}
+		}
+	}
+
+	blocks = append(blocks, textBlock("heading_2", "Transcript"))
+	return append(blocks, lineBlocks(rec.Transcript)...)
+}
+
+// lineBlocks returns a block per non-empty line, with bullet points as list items
+func lineBlocks(text string) []notionBlock {
+	var blocks []notionBlock
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if item, ok := cutBullet(line); ok {
+			blocks = append(blocks, textBlock("bulleted_list_item", item))
+		} else {
+			blocks = append(blocks, textBlock("paragraph", line))
+		}
+	}
+	return blocks
+}
+
+func cutBullet(line string) (string, bool) {
+	for _, bullet := range []string{"- ", "* ", "• "} {
+		if item, ok := strings.CutPrefix(line, bullet); ok {
+			return strings.TrimSpace(item), true
+		}
+	}
+	return line, false
+}
+
+func textBlock(kind, text string) notionBlock {
+	return notionBlock{
+		"object": "block",
+		"type":   kind,
+		kind:     map[string]any{"rich_text": richText(text)},
+	}
+}
+
+// richText splits text into text objects within the length limit
+func richText(text string) []map[string]any {
+	objects := []map[string]any{}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		n := min(maxNotionText, len(runes))
+		objects = append(objects, map[string]any{
+			"type": "text",
+			"text": map[string]string{"content": string(runes[:n])},
+		})
+		runes = runes[n:]
+	}
+	return objects
+}
@@ -0,0 +1,181 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// ObsidianConfig holds the configuration for Obsidian exports
+type ObsidianConfig struct {
+	// Vault is the directory of the vault, which must exist
+	Vault string
+	// Folder within the vault the notes are written to (default: the vault root)
+	Folder string
+}
+
+// Obsidian writes recordings as notes into an Obsidian vault, linking the
+// people, companies and products mentioned
+type Obsidian struct {
+	config ObsidianConfig
+}
+
+// NewObsidian creates a new Obsidian exporter
+func NewObsidian(config ObsidianConfig) *Obsidian {
+	return &Obsidian{config: config}
+}
+
+func (o *Obsidian) Name() string { return "obsidian" }
+
+// Export writes the note, replacing an earlier export of the recording
+func (o *Obsidian) Export(ctx context.Context, rec *Recording) error {
+	if _, err := os.Stat(o.config.Vault); err != nil {
+		return fmt.Errorf("opening vault: %w", err)
+	}
+	dir := filepath.Join(o.config.Vault, o.config.Folder)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating folder: %w", err)
+	}
+
+	path := filepath.Join(dir, noteName(rec)+".md")
+	if err := os.WriteFile(path, []byte(renderNote(rec)), 0644); err != nil {
+		return fmt.Errorf("writing note: %w", err)
+	}
+	return nil
+}
+
+// noteName names the note after the date and title of the recording
+func noteName(rec *Recording) string {
+	name := sanitizeLink(rec.Title())
+	if !rec.Metadata.Date.IsZero() {
+		name = rec.Metadata.Date.Format("2006-01-02") + " " + name
+	}
+	return name
+}
+
+func renderNote(rec *Recording) string {
+	var b strings.Builder
+	m := rec.Metadata
+
+	// Frontmatter values are written as JSON strings, which are valid YAML
+	b.WriteString("---\n")
+	if !m.Date.IsZero() {
+		fmt.Fprintf(&b, "date: %s\n", m.Date.Format("2006-01-02"))
+	}
+	if m.Category != "" {
+		fmt.Fprintf(&b, "category: %s\n", yamlString(m.Category))
+	}
+	if len(m.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, tag := range m.Tags {
+			if tag := tagName(tag); tag != "" {
+				fmt.Fprintf(&b, "  - %s\n", yamlString(tag))
+			}
+		}
+	}
+	if rec.Folder != "" {
+		fmt.Fprintf(&b, "recording: %s\n", yamlString(rec.Folder))
+	}
+	b.WriteString("---\n\n")
+
+	fmt.Fprintf(&b, "# %s\n\n", rec.Title())
+
+	if e := m.Entities; e != nil {
+		for _, group := range []struct {
+			label string
+			names []string
+		}{
+			{"People", e.People},
+			{"Companies", e.Companies},
+			{"Products", e.Products},
+		} {
+			if len(group.names) == 0 {
+				continue
+			}
+			links := make([]string, len(group.names))
+			for i, name := range group.names {
+				links[i] = wikilink(name)
+			}
+			fmt.Fprintf(&b, "**%s:** %s\n", group.label, strings.Join(links, ", "))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "## Summary\n\n%s\n\n", strings.TrimSpace(rec.Summary))
+
+	if a := rec.Actions; a != nil {
+		if len(a.ActionItems) > 0 {
+			b.WriteString("## Action Items\n\n")
+			for _, item := range a.ActionItems {
+				line := item.Task
+				if item.Owner != "" {
+					line = fmt.Sprintf("%s: %s", wikilink(item.Owner), line)
+				}
+				if item.Due != "" {
+					line += fmt.Sprintf(" (due %s)", item.Due)
+				}
+				fmt.Fprintf(&b, "- [ ] %s\n", line)
+			}
+			b.WriteString("\n")
+		}
+		if len(a.Decisions) > 0 {
+			b.WriteString("## Decisions\n\n")
+			for _, d := range a.Decisions {
+				fmt.Fprintf(&b, "- %s\n", d.Decision)
+			}
+			b.WriteString("\n")
+		}
+		if len(a.OpenQuestions) > 0 {
+			b.WriteString("## Open Questions\n\n")
+			for _, q := range a.OpenQuestions {
+				if q.Owner != "" {
+					fmt.Fprintf(&b, "- %s (%s)\n", q.Question, wikilink(q.Owner))
+				} else {
+					fmt.Fprintf(&b, "- %s\n", q.Question)
+				}
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "## Transcript\n\n%s\n", strings.TrimSpace(rec.Transcript))
+	return b.String()
+}
+
+func wikilink(name string) string {
+	return "[[" + sanitizeLink(name) + "]]"
+}
+
+// sanitizeLink removes the characters Obsidian does not allow in note names and links
+func sanitizeLink(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`*"\/<>:|?#^[]`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.TrimSpace(name)
+}
+
+// tagName turns a tag into an Obsidian tag, which has no spaces or punctuation
+// other than "-", "_" and "/"
+func tagName(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '/':
+			return r
+		case unicode.IsSpace(r):
+			return '-'
+		}
+		return -1
+	}, strings.TrimSpace(tag))
+}
+
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -0,0 +1,35 @@
+package export
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/r4h4/article-helper/internal/jsonapi"
+)
+
+// WebhookConfig holds the configuration for webhook exports
+type WebhookConfig struct {
+	URL string
+	// Header is sent with each request, e.g. an Authorization header
+	Header http.Header
+
+	// Transport sends the requests, default http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Webhook POSTs recordings as JSON to a URL
+type Webhook struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhook creates a new webhook exporter
+func NewWebhook(config WebhookConfig) *Webhook {
+	return &Webhook{config: config, client: newHTTPClient(config.Transport)}
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Export(ctx context.Context, rec *Recording) error {
+	return jsonapi.Send(ctx, w.client, http.MethodPost, w.config.URL, w.config.Header, rec, nil)
+}
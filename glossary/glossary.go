@@ -0,0 +1,175 @@
+package glossary
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Whisper only considers the last 224 tokens of the prompt; keep well below
+const maxPromptLength = 800
+
+// Term is a name or piece of jargon with its common misspellings
+type Term struct {
+	Term          string   `json:"term"`
+	Aliases       []string `json:"aliases,omitempty"`       // Misspellings and mishearings to correct
+	Pronunciation string   `json:"pronunciation,omitempty"` // How the term sounds, e.g. "koo-ber-NET-eez"
+}
+
+// Glossary is a list of terms applied during transcription and editing
+type Glossary struct {
+	Terms []Term
+}
+
+// Replacement records a correction made by Apply
+type Replacement struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// Load reads a glossary from a JSON file holding a list of terms, e.g.
+//
+//	[{"term": "Kubernetes", "aliases": ["cooper netties"], "pronunciation": "koo-ber-NET-eez"}]
+func Load(path string) (*Glossary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading glossary: %w", err)
+	}
+
+	var terms []Term
+	if err := json.Unmarshal(data, &terms); err != nil {
+		return nil, fmt.Errorf("parsing glossary %s: %w", path, err)
+	}
+	for i, t := range terms {
+		if strings.TrimSpace(t.Term) == "" {
+			return nil, fmt.Errorf("glossary %s: term %d is empty", path, i+1)
+		}
+	}
+	return &Glossary{Terms: terms}, nil
+}
+
+// Prompt returns the terms as a transcription prompt, which biases Whisper
+// towards their spelling
+func (g *Glossary) Prompt() string {
+	var b strings.Builder
+	b.WriteString("Glossary:")
+	for i, t := range g.Terms {
+		sep := " "
+		if i > 0 {
+			sep = ", "
+		}
+		if b.Len()+len(sep)+len(t.Term) > maxPromptLength {
+			break
+		}
+		b.WriteString(sep + t.Term)
+	}
+	return b.String() + "."
+}
+
+// String renders the glossary for an LLM prompt, one term per line
+func (g *Glossary) String() string {
+	var b strings.Builder
+	for _, t := range g.Terms {
+		b.WriteString("- " + t.Term)
+
+		var hints []string
+		if len(t.Aliases) > 0 {
+			hints = append(hints, "may be transcribed as: "+strings.Join(t.Aliases, ", "))
+		}
+		if t.Pronunciation != "" {
+			hints = append(hints, "pronounced: "+t.Pronunciation)
+		}
+		if len(hints) > 0 {
+			b.WriteString(" (" + strings.Join(hints, "; ") + ")")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Apply replaces aliases and wrongly cased occurrences of terms with the
+// correct spelling. Only whole words are matched, case-insensitively, in a
+// single pass preferring the longest match, so a term containing an alias,
+// e.g. "Acme Corp" with the alias "Acme", is not corrected again.
+func (g *Glossary) Apply(text string) (string, []Replacement) {
+	type rule struct {
+		alias string
+		term  string
+	}
+
+	var rules []rule
+	for _, t := range g.Terms {
+		for _, alias := range append([]string{t.Term}, t.Aliases...) {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				rules = append(rules, rule{alias: alias, term: t.Term})
+			}
+		}
+	}
+	// Longer aliases first, so "new york times" wins over "new york"
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].alias) > len(rules[j].alias) })
+
+	// match returns the rule matching a whole word at text[start:]
+	match := func(start int) (rule, int, bool) {
+		if before, _ := utf8.DecodeLastRuneInString(text[:start]); isWordRune(before) {
+			return rule{}, 0, false
+		}
+		for _, r := range rules {
+			end := start + len(r.alias)
+			if end <= len(text) && strings.EqualFold(text[start:end], r.alias) && isWordBoundary(text, start, end) {
+				return r, end, true
+			}
+		}
+		return rule{}, 0, false
+	}
+
+	counts := make(map[Replacement]int)
+	var order []Replacement
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(text); {
+		r, end, ok := match(i)
+		if !ok {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+
+		// Correct text is skipped as a whole
+		if found := text[i:end]; found != r.term {
+			b.WriteString(text[last:i])
+			b.WriteString(r.term)
+			last = end
+
+			key := Replacement{From: found, To: r.term}
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+		}
+		i = end
+	}
+	b.WriteString(text[last:])
+
+	report := make([]Replacement, len(order))
+	for i, key := range order {
+		key.Count = counts[key]
+		report[i] = key
+	}
+	return b.String(), report
+}
+
+// isWordBoundary reports whether text[start:end] is not part of a larger word
+func isWordBoundary(text string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	return !isWordRune(before) && !isWordRune(after)
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
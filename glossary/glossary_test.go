@@ -0,0 +1,74 @@
+package glossary
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	g := &Glossary{Terms: []Term{
+		{Term: "Kubernetes", Aliases: []string{"cooper netties", "kubernetis"}},
+		{Term: "Acme Corp", Aliases: []string{"acme"}},
+		{Term: "Zoë"},
+	}}
+
+	text := "We moved to cooper netties. Kubernetis at ACME is great, kubernetes too. Acmeology and zoë stay."
+	got, report := g.Apply(text)
+
+	expected := "We moved to Kubernetes. Kubernetes at Acme Corp is great, Kubernetes too. Acmeology and Zoë stay."
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// In the order of their first occurrence
+	expectedReport := []Replacement{
+		{From: "cooper netties", To: "Kubernetes", Count: 1},
+		{From: "Kubernetis", To: "Kubernetes", Count: 1},
+		{From: "ACME", To: "Acme Corp", Count: 1},
+		{From: "kubernetes", To: "Kubernetes", Count: 1},
+		{From: "zoë", To: "Zoë", Count: 1},
+	}
+	if !reflect.DeepEqual(report, expectedReport) {
+		t.Errorf("Expected report %+v, got %+v", expectedReport, report)
+	}
+}
+
+func TestApplyTermContainingAlias(t *testing.T) {
+	g := &Glossary{Terms: []Term{{Term: "Acme Corp", Aliases: []string{"Acme", "acme corporation"}}}}
+
+	got, report := g.Apply("Acme is great and so is Acme Corp. ACME CORPORATION agrees.")
+
+	expected := "Acme Corp is great and so is Acme Corp. Acme Corp agrees."
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	expectedReport := []Replacement{
+		{From: "Acme", To: "Acme Corp", Count: 1},
+		{From: "ACME CORPORATION", To: "Acme Corp", Count: 1},
+	}
+	if !reflect.DeepEqual(report, expectedReport) {
+		t.Errorf("Expected report %+v, got %+v", expectedReport, report)
+	}
+
+	// Applying the glossary again changes nothing
+	if again, report := g.Apply(got); again != got || len(report) != 0 {
+		t.Errorf("Expected no further corrections, got %q and %+v", again, report)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	g := &Glossary{Terms: []Term{{Term: "Kubernetes"}, {Term: "Acme Corp"}}}
+
+	if got := g.Prompt(); got != "Glossary: Kubernetes, Acme Corp." {
+		t.Errorf("Unexpected prompt %q", got)
+	}
+
+	long := &Glossary{}
+	for i := 0; i < 200; i++ {
+		long.Terms = append(long.Terms, Term{Term: strings.Repeat("x", 10)})
+	}
+	if got := long.Prompt(); len(got) > maxPromptLength+1 {
+		t.Errorf("Expected prompt of at most %d characters, got %d", maxPromptLength+1, len(got))
+	}
+}
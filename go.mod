@@ -3,18 +3,25 @@ module github.com/r4h4/article-helper
 go 1.22.5
 
 require (
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/ggerganov/whisper.cpp/bindings/go v0.0.0-20240709155418-d207c6882247
 	github.com/go-audio/wav v1.1.0
+	github.com/mewkiz/flac v1.0.12
+	golang.org/x/time v0.5.0
 )
 
-require github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
+require (
+	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
+	github.com/icza/bitio v1.1.0 // indirect
+	github.com/mewkiz/pkg v0.0.0-20230226050401-4010bf0fec14 // indirect
+)
 
 require (
 	github.com/cenkalti/backoff/v4 v4.3.0
-	github.com/eiannone/keyboard v0.0.0-20220611211555-0d226195f203 // indirect
-	github.com/go-audio/audio v1.0.0 // indirect
+	github.com/eiannone/keyboard v0.0.0-20220611211555-0d226195f203
+	github.com/go-audio/audio v1.0.0
 	github.com/go-audio/riff v1.0.0 // indirect
 	github.com/joho/godotenv v1.5.1
 	github.com/manifoldco/promptui v0.9.0
-	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
+	golang.org/x/sys v0.5.0 // indirect
 )
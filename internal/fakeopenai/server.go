@@ -0,0 +1,281 @@
+// Package fakeopenai provides a fake OpenAI API for tests. It replays
+// responses from cassette files and injects faults such as rate limits,
+// server errors, timeouts and malformed JSON.
+//
+// Cassettes are recorded against the real API by running the tests with
+// RECORD_CASSETTES=1 and OPENAI_API_KEY set. API keys are never recorded.
+package fakeopenai
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const upstreamURL = "https://api.openai.com"
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Path     string          `json:"path"`
+	Match    string          `json:"match,omitempty"` // Key of the request, empty matches all requests to Path
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Cassette is a list of recorded interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Fault replaces the response to a request
+type Fault struct {
+	Status int
+	Body   string
+	Delay  time.Duration // Before responding, longer than the client timeout to simulate a timeout
+}
+
+var (
+	RateLimited   = Fault{Status: http.StatusTooManyRequests, Body: `{"error": {"message": "Rate limit reached", "type": "requests"}}`}
+	ServerError   = Fault{Status: http.StatusInternalServerError, Body: `{"error": {"message": "The server had an error while processing your request"}}`}
+	MalformedJSON = Fault{Status: http.StatusOK, Body: `{"choices": [{"message": `}
+)
+
+// Timeout delays the response by d, after which it is replayed as usual
+func Timeout(d time.Duration) Fault {
+	return Fault{Delay: d}
+}
+
+// Request is a request received by the server
+type Request struct {
+	Path string
+	Key  string
+	Body []byte
+}
+
+// Server is a fake OpenAI API. Its base URL, e.g. for OPENAI_BASE_URL, is BaseURL().
+type Server struct {
+	*httptest.Server
+
+	t        testing.TB
+	path     string
+	record   bool
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+	faults   map[string][]Fault
+	requests []Request
+}
+
+// New starts a server replaying the cassette at path. The server is closed,
+// and a recorded cassette saved, when the test ends.
+func New(t testing.TB, path string) *Server {
+	t.Helper()
+
+	s := &Server{
+		t:      t,
+		path:   path,
+		record: os.Getenv("RECORD_CASSETTES") != "",
+		faults: make(map[string][]Fault),
+	}
+	if s.record {
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			t.Fatal("RECORD_CASSETTES requires OPENAI_API_KEY")
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Reading cassette: %v", err)
+		}
+		if err := json.Unmarshal(data, &s.cassette); err != nil {
+			t.Fatalf("Parsing cassette %s: %v", path, err)
+		}
+		s.used = make([]bool, len(s.cassette.Interactions))
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(func() {
+		s.Server.Close()
+		if s.record {
+			s.save()
+		}
+	})
+	return s
+}
+
+// BaseURL returns the URL to use instead of https://api.openai.com/v1
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// Inject makes the next requests to path, e.g. "/v1/chat/completions", fail
+// with the given faults, one per request
+func (s *Server) Inject(path string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = append(s.faults[path], faults...)
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer") || strings.TrimSpace(strings.TrimPrefix(auth, "Bearer")) == "" {
+		writeResponse(w, http.StatusUnauthorized, []byte(`{"error": {"message": "You didn't provide an API key."}}`))
+		return
+	}
+
+	key := requestKey(body)
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Path: r.URL.Path, Key: key, Body: body})
+	var fault *Fault
+	if faults := s.faults[r.URL.Path]; len(faults) > 0 {
+		fault, s.faults[r.URL.Path] = &faults[0], faults[1:]
+	}
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			writeResponse(w, fault.Status, []byte(fault.Body))
+			return
+		}
+	}
+
+	if s.record {
+		s.forward(w, r, body, key)
+		return
+	}
+
+	interaction, ok := s.find(r.URL.Path, key)
+	if !ok {
+		s.t.Errorf("fakeopenai: no interaction in %s for %s %q", s.path, r.URL.Path, key)
+		writeResponse(w, http.StatusNotFound, []byte(`{"error": {"message": "not in cassette"}}`))
+		return
+	}
+	writeResponse(w, interaction.Status, interaction.Response)
+}
+
+// find returns the first unused interaction matching the request. Once all
+// are used, the last one is replayed again, e.g. for retries.
+func (s *Server) find(path, key string) (Interaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := -1
+	for i, in := range s.cassette.Interactions {
+		if in.Path != path || (in.Match != "" && in.Match != key) {
+			continue
+		}
+		if !s.used[i] {
+			s.used[i] = true
+			return in, true
+		}
+		last = i
+	}
+	if last < 0 {
+		return Interaction{}, false
+	}
+	return s.cassette.Interactions[last], true
+}
+
+// forward sends the request to the real API and records the response
+func (s *Server) forward(w http.ResponseWriter, r *http.Request, body []byte, key string) {
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, upstreamURL+r.URL.Path, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("OPENAI_API_KEY"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	// Cassettes embed the responses, so only JSON can be recorded
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, respBody); err != nil {
+		s.t.Errorf("fakeopenai: response to %s is not JSON: %v", r.URL.Path, err)
+	} else {
+		s.mu.Lock()
+		s.cassette.Interactions = append(s.cassette.Interactions, Interaction{
+			Path:     r.URL.Path,
+			Match:    key,
+			Status:   resp.StatusCode,
+			Response: compacted.Bytes(),
+		})
+		s.mu.Unlock()
+	}
+	writeResponse(w, resp.StatusCode, respBody)
+}
+
+func (s *Server) save() {
+	data, err := json.MarshalIndent(s.cassette, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(s.path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		s.t.Errorf("fakeopenai: saving cassette: %v", err)
+	}
+}
+
+// requestKey identifies a chat completion by the first line of its last
+// message, which names the task of the prompt. Other requests have no key.
+func requestKey(body []byte) string {
+	var req struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || len(req.Messages) == 0 {
+		return ""
+	}
+
+	content := req.Messages[len(req.Messages)-1].Content
+	key, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	if len(key) > 100 {
+		key = key[:100]
+	}
+	return key
+}
+
+func writeResponse(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,39 @@
+// Package filelock serializes writes to files shared by several processes,
+// e.g. parallel pipeline runs updating the library index.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	pollInterval = 10 * time.Millisecond
+	// staleAfter is how old a lock must be to be considered left behind by a
+	// crashed process. Writes hold the lock for far less.
+	staleAfter = 30 * time.Second
+)
+
+// Lock creates the lock file path+".lock", waiting while another process or
+// goroutine holds it, and returns the function removing it again.
+func Lock(path string) (unlock func(), err error) {
+	lockPath := path + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("locking %s: %w", path, err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleAfter {
+			os.Remove(lockPath)
+			continue
+		}
+		time.Sleep(pollInterval)
+	}
+}
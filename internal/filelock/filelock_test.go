@@ -0,0 +1,72 @@
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	if err := os.WriteFile(path, []byte("0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unlocked, concurrent read-modify-write cycles would lose increments
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := Lock(path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer unlock()
+
+			data, _ := os.ReadFile(path)
+			n, _ := strconv.Atoi(string(data))
+			time.Sleep(time.Millisecond)
+			os.WriteFile(path, []byte(strconv.Itoa(n+1)), 0644)
+		}()
+	}
+	wg.Wait()
+
+	if data, _ := os.ReadFile(path); string(data) != "20" {
+		t.Errorf("Expected 20 increments, got %s", data)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be removed, got %v", err)
+	}
+}
+
+func TestLockStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		unlock, err := Lock(path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		unlock()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the stale lock to be broken")
+	}
+}
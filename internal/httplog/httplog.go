@@ -0,0 +1,93 @@
+// Package httplog logs HTTP requests and responses, with credentials redacted.
+package httplog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxBody is the number of bytes of a body that are logged
+const maxBody = 16 * 1024
+
+// redactedHeaders carry credentials
+var redactedHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "Cookie", "Set-Cookie"}
+
+// Transport logs the requests sent through it at debug level
+type Transport struct {
+	Base   http.RoundTripper // Default http.DefaultTransport
+	Logger *slog.Logger
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// The body of the request itself is left to the base transport
+	var reqBody []byte
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	attrs := []any{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.Any("request_headers", redact(req.Header)),
+		slog.String("request_body", formatBody(req.Header.Get("Content-Type"), reqBody)),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		t.Logger.Debug("HTTP request failed", append(attrs, slog.Any("err", err))...)
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		return nil, err
+	}
+
+	t.Logger.Debug("HTTP request", append(attrs,
+		slog.Int("status", resp.StatusCode),
+		slog.Any("response_headers", redact(resp.Header)),
+		slog.String("response_body", formatBody(resp.Header.Get("Content-Type"), respBody)),
+	)...)
+	return resp, nil
+}
+
+// redact returns a copy of the headers without credentials
+func redact(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range redactedHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "REDACTED")
+		}
+	}
+	return redacted
+}
+
+// formatBody returns a text body, truncated to maxBody, or a description of a binary one
+func formatBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if !strings.Contains(contentType, "json") && !strings.HasPrefix(contentType, "text/") {
+		return fmt.Sprintf("<%d bytes of %s>", len(body), contentType)
+	}
+	if len(body) > maxBody {
+		return fmt.Sprintf("%s... (%d bytes)", body[:maxBody], len(body))
+	}
+	return string(body)
+}
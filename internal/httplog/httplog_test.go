@@ -0,0 +1,69 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"echo": ` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	client := &http.Client{Transport: &Transport{Logger: slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))}}
+
+	req, err := http.NewRequest("POST", server.URL+"/v1/chat/completions", strings.NewReader(`{"model": "gpt-4o"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// The response is still readable after logging
+	if string(body) != `{"echo": {"model": "gpt-4o"}}` {
+		t.Errorf("Unexpected response %s", body)
+	}
+
+	if strings.Contains(out.String(), "sk-secret") {
+		t.Errorf("Expected the API key to be redacted: %s", out.String())
+	}
+	var record struct {
+		Status         int                 `json:"status"`
+		RequestHeaders map[string][]string `json:"request_headers"`
+		RequestBody    string              `json:"request_body"`
+		ResponseBody   string              `json:"response_body"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Parsing log %s: %v", out.String(), err)
+	}
+	if record.Status != 200 || record.RequestHeaders["Authorization"][0] != "REDACTED" ||
+		record.RequestBody != `{"model": "gpt-4o"}` || record.ResponseBody != string(body) {
+		t.Errorf("Unexpected log record %+v", record)
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	if body := formatBody("multipart/form-data; boundary=x", make([]byte, 2048)); body != "<2048 bytes of multipart/form-data; boundary=x>" {
+		t.Errorf("Expected binary bodies to be described, got %q", body)
+	}
+	if body := formatBody("text/plain", bytes.Repeat([]byte("a"), maxBody+1)); !strings.HasSuffix(body, fmt.Sprintf("... (%d bytes)", maxBody+1)) {
+		t.Errorf("Expected long bodies to be truncated, got %d bytes", len(body))
+	}
+}
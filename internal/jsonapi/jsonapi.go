@@ -0,0 +1,61 @@
+// Package jsonapi sends JSON requests to HTTP APIs.
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxExcerpt is the number of bytes of a response body quoted in errors
+const maxExcerpt = 200
+
+// Excerpt returns the start of a response body for error messages
+func Excerpt(body []byte) string {
+	if len(body) > maxExcerpt {
+		return fmt.Sprintf("%s... (%d bytes)", body[:maxExcerpt], len(body))
+	}
+	return string(body)
+}
+
+// Send sends in as JSON with the given header and decodes the response into
+// out, if not nil. A status other than 2xx is an error quoting the response.
+func Send(ctx context.Context, client *http.Client, method, url string, header http.Header, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("error marshaling request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response (status %d): %s", resp.StatusCode, Excerpt(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return nil
+}
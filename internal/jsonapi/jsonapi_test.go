@@ -0,0 +1,40 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Token") != "secret" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		var in struct{ Name string }
+		json.NewDecoder(r.Body).Decode(&in)
+		if in.Name == "fail" {
+			http.Error(w, strings.Repeat("x", 300), http.StatusTeapot)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"greeting": "Hello " + in.Name})
+	}))
+	defer server.Close()
+
+	header := http.Header{"X-Token": {"secret"}}
+	var out struct{ Greeting string }
+	if err := Send(context.Background(), server.Client(), http.MethodPost, server.URL, header, map[string]string{"name": "Alice"}, &out); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if out.Greeting != "Hello Alice" {
+		t.Errorf("Unexpected response %+v", out)
+	}
+
+	err := Send(context.Background(), server.Client(), http.MethodPost, server.URL, header, map[string]string{"name": "fail"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 418") || !strings.HasSuffix(err.Error(), "... (301 bytes)") {
+		t.Errorf("Expected a status error with an excerpt of the body, got %v", err)
+	}
+}
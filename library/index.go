@@ -0,0 +1,113 @@
+package library
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Field weights: a hit in the headline or tags says more than one in the transcript
+const (
+	headlineWeight   = 3
+	tagWeight        = 3
+	summaryWeight    = 2
+	transcriptWeight = 1
+)
+
+// invertedIndex maps terms to the entries containing them
+type invertedIndex struct {
+	postings map[string]map[string]float64 // term -> entry ID -> weighted frequency
+	terms    map[string][]string           // entry ID -> terms, for removal
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		postings: make(map[string]map[string]float64),
+		terms:    make(map[string][]string),
+	}
+}
+
+func (ix *invertedIndex) add(e *Entry) {
+	ix.remove(e.ID)
+
+	freqs := make(map[string]float64)
+	count := func(text string, weight float64) {
+		for _, t := range tokenize(text) {
+			freqs[t] += weight
+		}
+	}
+	count(e.Headline, headlineWeight)
+	count(strings.Join(e.Tags, " "), tagWeight)
+	count(e.Category, tagWeight)
+	count(e.Summary, summaryWeight)
+	count(e.Transcript, transcriptWeight)
+
+	for t, f := range freqs {
+		if ix.postings[t] == nil {
+			ix.postings[t] = make(map[string]float64)
+		}
+		ix.postings[t][e.ID] = f
+		ix.terms[e.ID] = append(ix.terms[e.ID], t)
+	}
+}
+
+func (ix *invertedIndex) remove(id string) {
+	for _, t := range ix.terms[id] {
+		delete(ix.postings[t], id)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+		}
+	}
+	delete(ix.terms, id)
+}
+
+// search scores the entries containing every query term with TF-IDF. Query
+// terms also match indexed terms they are a prefix of, so "pric" finds "pricing".
+func (ix *invertedIndex) search(query []string) map[string]float64 {
+	if len(query) == 0 {
+		return nil
+	}
+
+	total := float64(len(ix.terms))
+	var scores map[string]float64
+	for i, q := range query {
+		termScores := make(map[string]float64)
+		for term, postings := range ix.postings {
+			if !strings.HasPrefix(term, q) {
+				continue
+			}
+			idf := math.Log(1 + total/float64(len(postings)))
+			for id, f := range postings {
+				termScores[id] += (1 + math.Log(f)) * idf
+			}
+		}
+
+		if i == 0 {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := termScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores
+}
+
+// tokenize splits text into lowercase words of at least two characters
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) >= 2 {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
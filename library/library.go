@@ -0,0 +1,240 @@
+package library
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r4h4/article-helper/internal/filelock"
+)
+
+// IndexFile is the name of the index in the library root
+const IndexFile = "index.json"
+
+// Entry is a recording in the library
+type Entry struct {
+	ID         string    `json:"id"` // Name of the recording folder
+	Date       time.Time `json:"date"`
+	Headline   string    `json:"headline,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	Category   string    `json:"category,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Transcript string    `json:"transcript,omitempty"`
+}
+
+// Filter restricts listings and searches. Zero fields match everything.
+type Filter struct {
+	Since    time.Time // Inclusive
+	Until    time.Time // Exclusive
+	Tag      string
+	Category string
+}
+
+func (f Filter) matches(e *Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Date.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Date.Before(f.Until):
+		return false
+	case f.Category != "" && !strings.EqualFold(f.Category, e.Category):
+		return false
+	case f.Tag != "" && !containsFold(e.Tags, f.Tag):
+		return false
+	}
+	return true
+}
+
+// Library indexes the recordings below its root directory. Entries are
+// persisted in index.json; the inverted index is rebuilt in memory on Open.
+type Library struct {
+	root    string
+	mu      sync.Mutex
+	entries map[string]*Entry
+	index   *invertedIndex
+}
+
+// Open loads the library rooted at dir. A missing index yields an empty library.
+func Open(dir string) (*Library, error) {
+	l := &Library{root: dir}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Root returns the directory holding the recordings
+func (l *Library) Root() string {
+	return l.root
+}
+
+// Add adds or replaces an entry and saves the index
+func (l *Library) Add(e *Entry) error {
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Pick up entries added by other processes since Open
+	if err := l.load(); err != nil {
+		return err
+	}
+	l.entries[e.ID] = e
+	l.index.add(e)
+	return l.save()
+}
+
+// Rebuild replaces all entries, e.g. after reindexing the recording folders
+func (l *Library) Rebuild(entries []*Entry) error {
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = make(map[string]*Entry, len(entries))
+	l.index = newInvertedIndex()
+	for _, e := range entries {
+		l.entries[e.ID] = e
+		l.index.add(e)
+	}
+	return l.save()
+}
+
+// Get returns the entry with the given ID, or the only entry whose ID starts with it
+func (l *Library) Get(id string) (*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[id]; ok {
+		return e, nil
+	}
+
+	var found *Entry
+	for key, e := range l.entries {
+		if strings.HasPrefix(key, id) {
+			if found != nil {
+				return nil, fmt.Errorf("recording %q is ambiguous", id)
+			}
+			found = e
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("recording %q not found", id)
+	}
+	return found, nil
+}
+
+// List returns the entries matching the filter, newest first
+func (l *Library) List(f Filter) []*Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []*Entry
+	for _, e := range l.entries {
+		if f.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Date.After(entries[j].Date) })
+	return entries
+}
+
+// Result is a search hit
+type Result struct {
+	Entry *Entry
+	Score float64
+	Terms []string // Query terms, for highlighting
+}
+
+// Search returns the entries matching all words of the query, best match first
+func (l *Library) Search(query string, f Filter) []Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	terms := tokenize(query)
+	scores := l.index.search(terms)
+
+	var results []Result
+	for id, score := range scores {
+		if e := l.entries[id]; e != nil && f.matches(e) {
+			results = append(results, Result{Entry: e, Score: score, Terms: terms})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Entry.Date.After(results[j].Entry.Date)
+	})
+	return results
+}
+
+// lock serializes writes of the index by all libraries, also in other
+// processes, e.g. of parallel pipeline runs
+func (l *Library) lock() (unlock func(), err error) {
+	if err := os.MkdirAll(l.root, 0755); err != nil {
+		return nil, fmt.Errorf("creating library directory: %w", err)
+	}
+	return filelock.Lock(filepath.Join(l.root, IndexFile))
+}
+
+func (l *Library) load() error {
+	l.entries = make(map[string]*Entry)
+	l.index = newInvertedIndex()
+
+	data, err := os.ReadFile(filepath.Join(l.root, IndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("reading library index: %w", err)
+	}
+
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("parsing library index: %w", err)
+	}
+	for _, e := range entries {
+		l.entries[e.ID] = e
+		l.index.add(e)
+	}
+	return nil
+}
+
+// save atomically writes the entries, oldest first, so diffs stay small
+func (l *Library) save() error {
+	entries := make([]*Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding library index: %w", err)
+	}
+	path := filepath.Join(l.root, IndexFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("writing library index: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func containsFold(items []string, s string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
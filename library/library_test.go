@@ -0,0 +1,153 @@
+package library
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestLibrary(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	entries := []*Entry{
+		{
+			ID:         "20260305_101500_Pricing_Strategy",
+			Date:       time.Date(2026, 3, 5, 10, 15, 0, 0, time.UTC),
+			Headline:   "Pricing Strategy",
+			Tags:       []string{"pricing", "sales"},
+			Category:   "meeting",
+			Transcript: "We should raise prices for the enterprise plan next quarter.",
+		},
+		{
+			ID:         "20260412_090000_Garden_Ideas",
+			Date:       time.Date(2026, 4, 12, 9, 0, 0, 0, time.UTC),
+			Headline:   "Garden Ideas",
+			Category:   "idea",
+			Transcript: "Plant tomatoes, and check the pricing of raised beds.",
+		},
+	}
+	for _, e := range entries {
+		if err := lib.Add(e); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	// Reopen to make sure the index was persisted
+	lib, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	t.Run("Search", func(t *testing.T) {
+		results := lib.Search("pric", Filter{})
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(results))
+		}
+		if results[0].Entry.ID != entries[0].ID {
+			t.Errorf("Expected the headline match first, got %s", results[0].Entry.ID)
+		}
+
+		if results := lib.Search("pricing tomatoes", Filter{}); len(results) != 1 || results[0].Entry.ID != entries[1].ID {
+			t.Errorf("Expected only the garden recording to match all terms, got %v", results)
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		march := Filter{
+			Since: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		}
+		if results := lib.Search("pricing", march); len(results) != 1 || results[0].Entry.ID != entries[0].ID {
+			t.Errorf("Expected only the March recording, got %v", results)
+		}
+
+		if list := lib.List(Filter{Category: "IDEA"}); len(list) != 1 || list[0].ID != entries[1].ID {
+			t.Errorf("Expected only the idea, got %v", list)
+		}
+
+		if list := lib.List(Filter{}); len(list) != 2 || list[0].ID != entries[1].ID {
+			t.Errorf("Expected both recordings newest first, got %v", list)
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		e, err := lib.Get("20260305")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if e.ID != entries[0].ID {
+			t.Errorf("Expected %s, got %s", entries[0].ID, e.ID)
+		}
+
+		if _, err := lib.Get("2026"); err == nil {
+			t.Error("Expected an ambiguous prefix to fail")
+		}
+	})
+}
+
+// TestAddProcesses adds entries from several processes at once, none of
+// which may be lost
+func TestAddProcesses(t *testing.T) {
+	if dir := os.Getenv("LIBRARY_TEST_DIR"); dir != "" {
+		addFromProcess(t, dir, os.Getenv("LIBRARY_TEST_ID"))
+		return
+	}
+
+	dir := t.TempDir()
+	const processes = 8
+	cmds := make([]*exec.Cmd, processes)
+	for i := range cmds {
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestAddProcesses$")
+		cmds[i].Env = append(os.Environ(), "LIBRARY_TEST_DIR="+dir, fmt.Sprintf("LIBRARY_TEST_ID=%d", i))
+		if err := cmds[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+	}
+
+	lib, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if list := lib.List(Filter{}); len(list) != processes*5 {
+		t.Errorf("Expected %d entries, got %d", processes*5, len(list))
+	}
+}
+
+func addFromProcess(t *testing.T, dir, id string) {
+	// Opened before the other processes add their entries
+	lib, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for i := range 5 {
+		if err := lib.Add(&Entry{ID: fmt.Sprintf("%s_%d", id, i), Transcript: "Entry"}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := "First we talked about the weather. Then we discussed pricing for the enterprise plan and agreed to revisit Pricing in May."
+	mark := func(s string) string { return "[" + s + "]" }
+
+	got := Snippet(text, []string{"pric"}, 40, mark)
+	expected := "…weather. Then we discussed [pricing] for the enterprise…"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if got := Snippet("Short text", []string{"missing"}, 40, mark); got != "Short text" {
+		t.Errorf("Expected the text unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package library
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Snippet returns an excerpt of about width characters around the first
+// occurrence of any of the terms, with every occurrence passed through mark.
+// Terms match at the start of words, like in Search.
+func Snippet(text string, terms []string, width int, mark func(string) string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	matches := findTerms(text, terms)
+
+	// Center the excerpt on the first match
+	start := 0
+	if len(matches) > 0 {
+		start = max(0, matches[0][0]-width/2)
+	}
+	end := min(len(text), start+width)
+	start, end = alignRune(text, start), alignRune(text, end)
+
+	// Extend to whole words
+	for start > 0 && text[start-1] != ' ' {
+		start--
+	}
+	for end < len(text) && text[end] != ' ' {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	last := start
+	for _, m := range matches {
+		if m[0] < start || m[1] > end {
+			continue
+		}
+		b.WriteString(text[last:m[0]])
+		b.WriteString(mark(text[m[0]:m[1]]))
+		last = m[1]
+	}
+	b.WriteString(text[last:end])
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// findTerms returns the byte ranges of the words starting with any term
+func findTerms(text string, terms []string) [][2]int {
+	lower := strings.ToLower(text)
+
+	var matches [][2]int
+	for i := 0; i < len(lower); {
+		r, size := utf8.DecodeRuneInString(lower[i:])
+		if !isWordRune(r) {
+			i += size
+			continue
+		}
+
+		// Find the end of the word
+		j := i
+		for j < len(lower) {
+			r, size := utf8.DecodeRuneInString(lower[j:])
+			if !isWordRune(r) {
+				break
+			}
+			j += size
+		}
+
+		// ToLower may change byte lengths for some scripts; only highlight when it did not
+		if len(lower) == len(text) {
+			for _, t := range terms {
+				if strings.HasPrefix(lower[i:j], t) {
+					matches = append(matches, [2]int{i, j})
+					break
+				}
+			}
+		}
+		i = j
+	}
+	return matches
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func alignRune(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// httpLogFile holds the API requests and responses of a recording, with -log-http
+const httpLogFile = "http.log"
+
+// setupLogging configures the default logger, which writes to stderr so
+// results on stdout can be piped
+func setupLogging(verbose, quiet bool, format string) error {
+	if verbose && quiet {
+		return fmt.Errorf("-verbose and -quiet cannot be combined")
+	}
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if verbose {
+		opts.Level = slog.LevelDebug
+	} else if quiet {
+		opts.Level = slog.LevelWarn
+	}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("unknown log format %q, use text or json", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// folderLog appends to a file in the recording folder. The file is opened for
+// every write, as the folder is renamed during the run.
+type folderLog struct {
+	state *State
+	name  string
+}
+
+func (l *folderLog) Write(p []byte) (int, error) {
+	f, err := os.OpenFile(filepath.Join(l.state.OutFolder, l.name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := f.Write(p)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
@@ -2,18 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/r4h4/article-helper/config"
+	"github.com/r4h4/article-helper/downloader"
+	"github.com/r4h4/article-helper/embed"
+	"github.com/r4h4/article-helper/encoder"
+	"github.com/r4h4/article-helper/glossary"
+	"github.com/r4h4/article-helper/recording"
+	"github.com/r4h4/article-helper/transcript"
+	"github.com/r4h4/article-helper/usage"
 )
 
 func main() {
 	if err := run(); err != nil {
-		log.Fatalf("Error: %v", err)
+		slog.Error(err.Error())
+		os.Exit(1)
 	}
 }
 
@@ -23,36 +37,255 @@ func run() error {
 	}
 
 	outputFile := flag.String("o", "", "Output file name (default: current timestamp)")
+	device := flag.String("device", "", "Input device to record from (default: configured device)")
+	autoStop := flag.Duration("auto-stop", 0, "Stop recording after this much silence following speech (0 disables)")
+	trim := flag.Bool("trim", true, "Trim leading and trailing silence before transcribing")
+	diarize := flag.Bool("diarize", false, "Attribute the transcript to speakers")
+	speakers := flag.String("speakers", "", "Speaker names, e.g. SPEAKER_1=Alice,SPEAKER_2=Bob")
+	speakersFile := flag.String("speakers-file", "", "JSON file mapping speaker labels to names")
+	actions := flag.Bool("actions", false, "Extract action items, decisions and open questions")
+	tags := flag.Bool("tags", false, "Extract tags, named entities and a category")
+	embedFlag := flag.Bool("embed", false, "Embed the cleaned transcript for semantic search with the ask command")
+	language := flag.String("language", "", "Spoken language as ISO-639-1 code, e.g. de (default: detect)")
+	translateTo := flag.String("translate-to", "", "Comma-separated languages to translate the cleaned transcript and summary into, e.g. english,french")
+	glossaryFile := flag.String("glossary", "", "Glossary file with names and terms to spell correctly (default: configured glossary)")
+	skip := flag.String("skip", "", "Comma-separated steps not to run, e.g. headline,rename (default: configured steps)")
+	uploadFormat := flag.String("upload-format", "", "Format to encode the recording to before upload: wav, flac, ogg, webm or mp3 (default: configured format or flac)")
+	profileName := flag.String("profile", "", "Profile of the config selecting where results are exported and delivered (default: the default profile, if configured)")
+	headlines := flag.Int("headlines", defaultHeadlineCandidates, "Number of ranked headline candidates to create per style")
+	review := flag.Bool("review", false, "Review and revise the transcript, summary and headline before saving")
+	verbose := flag.Bool("verbose", false, "Log debug messages")
+	quiet := flag.Bool("quiet", false, "Log only warnings and errors")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	logHTTP := flag.Bool("log-http", false, "Log API requests and responses, without API keys, to http.log in the recording folder")
 	flag.Parse()
 
+	if err := setupLogging(*verbose, *quiet, *logFormat); err != nil {
+		return err
+	}
+
+	configPath, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+
+	// Steps stop on the first Ctrl-C, a second one quits immediately
+	ctx := downloader.ContextForSignal(os.Interrupt, syscall.SIGTERM)
+
+	switch flag.Arg(0) {
+	case "", "serve", "process", "watch", "batch":
+	case "devices":
+		return runDevices(cfg, configPath, flag.Args()[1:])
+	case "repair":
+		return runRepair(flag.Args()[1:])
+	case "list":
+		return runList(flag.Args()[1:])
+	case "search":
+		return runSearch(flag.Args()[1:])
+	case "show":
+		return runShow(flag.Args()[1:])
+	case "reindex":
+		return runReindex(flag.Args()[1:])
+	case "embed":
+		return runEmbed(ctx, cfg, flag.Args()[1:])
+	case "ask":
+		return runAsk(ctx, cfg, flag.Args()[1:])
+	case "costs":
+		return runCosts(flag.Args()[1:])
+	default:
+		return fmt.Errorf("unknown command: %s", flag.Arg(0))
+	}
+
+	if *device == "" {
+		*device = cfg.Device
+	}
+	if *uploadFormat == "" {
+		*uploadFormat = cfg.UploadFormat
+	}
+	if *uploadFormat == "" {
+		*uploadFormat = string(encoder.FLAC)
+	}
+	format, err := encoder.ParseFormat(*uploadFormat)
+	if err != nil {
+		return err
+	}
+
+	if *glossaryFile == "" {
+		*glossaryFile = cfg.Glossary
+	}
+	var terms *glossary.Glossary
+	if *glossaryFile != "" {
+		if terms, err = glossary.Load(*glossaryFile); err != nil {
+			return err
+		}
+	}
+
+	speakerNames, err := loadSpeakerNames(*speakers, *speakersFile)
+	if err != nil {
+		return err
+	}
+
+	profile, err := cfg.Profile(*profileName)
+	if err != nil {
+		return err
+	}
+
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return fmt.Errorf("OPENAI_API_KEY environment variable is not set")
 	}
 
-	ctx := context.Background()
-	timestamp := time.Now().Format("20060102_150405")
+	opts := pipelineOptions{
+		APIKey:          apiKey,
+		BaseURL:         openAIBaseURL(),
+		OutputFile:      *outputFile,
+		Device:          *device,
+		AutoStopSilence: *autoStop,
+		Trim:            *trim,
+		Format:          format,
+		Language:        *language,
+		Glossary:        terms,
+		Diarize:         *diarize,
+		SpeakerNames:    speakerNames,
+		TranslateTo:     splitList(*translateTo),
+		Actions:         *actions,
+		Tags:            *tags,
+		Taxonomy:        cfg.Taxonomy,
+		Exports:         profile.Exports,
+		Deliveries:      profile.Deliveries,
+		Plugins:         cfg.Plugins,
+		Skip:            cfg.SkipSteps,
+		Prices:          cfg.Prices,
+		Headlines:       *headlines,
+		Review:          *review,
+		LogHTTP:         *logHTTP,
+	}
+	if *skip != "" {
+		opts.Skip = splitList(*skip)
+	}
+	if *embedFlag {
+		opts.Embed = newEmbedClient(cfg)
+	}
 
-	pipeline := []Step{
-		&RecordStep{OutputFile: outputFile},
-		&TranscribeStep{APIKey: apiKey},
-		&EditStep{APIKey: apiKey},
-		&SaveStep{},
-		&HeadlineStep{APIKey: apiKey},
+	if opts.Review && flag.Arg(0) != "" && flag.Arg(0) != "process" {
+		return fmt.Errorf("-review needs a terminal and cannot be used with %s", flag.Arg(0))
+	}
+	// Fail early on e.g. an invalid skip list, rather than on every file or job
+	clients, err := newClients(opts, nil, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := newPipeline(opts, clients); err != nil {
+		return err
 	}
 
-	state := &State{
-		Timestamp: timestamp,
-		OutFolder: fmt.Sprintf("./recordings/%s", timestamp),
+	switch flag.Arg(0) {
+	case "serve":
+		return runServe(ctx, opts, flag.Args()[1:])
+	case "watch":
+		return runWatch(ctx, opts, flag.Args()[1:])
+	case "batch":
+		return runBatch(ctx, opts, flag.Args()[1:])
+	case "process":
+		if flag.NArg() != 2 {
+			return fmt.Errorf("usage: process <audio file>")
+		}
+		opts.Input = flag.Arg(1)
+	}
+
+	state, pipeline, err := newRun(opts)
+	if err != nil {
+		return err
+	}
+	err = runPipeline(ctx, pipeline, state, nil)
+	printUsage(state.Usage.Summary())
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("interrupted, state saved to %s", filepath.Join(state.OutFolder, checkpointFile))
+	}
+	return err
+}
+
+// newState reserves a recording folder named after the current time. Runs
+// started within the same second, e.g. by the server, get the following seconds.
+func newState(tracker *usage.Tracker) (*State, error) {
+	if err := os.MkdirAll(recordingsDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating recordings folder: %w", err)
 	}
 
-	for _, step := range pipeline {
-		if err := step.Execute(ctx, state); err != nil {
-			return fmt.Errorf("%T: %w", step, err)
+	for t := time.Now(); ; t = t.Add(time.Second) {
+		timestamp := t.Format(recording.TimestampLayout)
+		folder := filepath.Join(recordingsDir, timestamp)
+
+		// Folders are renamed to timestamp_headline once the headline is known
+		if existing, _ := filepath.Glob(folder + "*"); len(existing) > 0 {
+			continue
+		}
+		err := os.Mkdir(folder, 0755)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("creating output folder: %w", err)
+		}
+		return &State{Timestamp: timestamp, OutFolder: folder, Usage: tracker}, nil
+	}
+}
+
+// loadSpeakerNames merges the speaker names from the sidecar file and the flag,
+// with the flag taking precedence
+func loadSpeakerNames(flagValue, file string) (map[string]string, error) {
+	names := make(map[string]string)
+	if file != "" {
+		fromFile, err := transcript.LoadSpeakerNames(file)
+		if err != nil {
+			return nil, err
 		}
+		maps.Copy(names, fromFile)
 	}
 
-	return nil
+	fromFlag, err := transcript.ParseSpeakerNames(flagValue)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(names, fromFlag)
+	return names, nil
+}
+
+// newEmbedClient creates the embeddings client for semantic search. The
+// OpenAI key is only sent to OpenAI; other servers get EMBEDDING_API_KEY.
+func newEmbedClient(cfg *config.Config) *embed.Client {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if cfg.EmbeddingEndpoint != "" {
+		apiKey = os.Getenv("EMBEDDING_API_KEY")
+	}
+	return embed.NewClient(embed.Config{
+		APIEndpoint: cfg.EmbeddingEndpoint,
+		APIKey:      apiKey,
+		Model:       cfg.EmbeddingModel,
+	})
+}
+
+// openAIBaseURL returns the OpenAI API base URL, which OPENAI_BASE_URL
+// overrides, e.g. for a proxy
+func openAIBaseURL() string {
+	if url := os.Getenv("OPENAI_BASE_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return defaultBaseURL
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // Artifacts of local mode
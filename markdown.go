@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// renderMarkdown renders the results of a run as a single Markdown document
+func renderMarkdown(state *State) string {
+	var b strings.Builder
+
+	title := state.Headline
+	if title == "" {
+		title = "Recording " + state.Timestamp
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+
+	if t := state.Tags; t != nil {
+		if t.Category != "" {
+			fmt.Fprintf(&b, "**Category:** %s\n\n", t.Category)
+		}
+		if len(t.Tags) > 0 {
+			fmt.Fprintf(&b, "**Tags:** %s\n\n", strings.Join(t.Tags, ", "))
+		}
+	}
+
+	fmt.Fprintf(&b, "## Summary\n\n%s\n\n", strings.TrimSpace(state.Summary))
+
+	if a := state.Actions; a != nil {
+		if len(a.ActionItems) > 0 {
+			b.WriteString("## Action Items\n\n")
+			for _, item := range a.ActionItems {
+				line := item.Task
+				if item.Owner != "" {
+					line = fmt.Sprintf("**%s**: %s", item.Owner, line)
+				}
+				if item.Due != "" {
+					line += fmt.Sprintf(" (due %s)", item.Due)
+				}
+				fmt.Fprintf(&b, "- [ ] %s\n", line)
+			}
+			b.WriteString("\n")
+		}
+
+		if len(a.Decisions) > 0 {
+			b.WriteString("## Decisions\n\n")
+			for _, d := range a.Decisions {
+				fmt.Fprintf(&b, "- %s\n", d.Decision)
+			}
+			b.WriteString("\n")
+		}
+
+		if len(a.OpenQuestions) > 0 {
+			b.WriteString("## Open Questions\n\n")
+			for _, q := range a.OpenQuestions {
+				if q.Owner != "" {
+					fmt.Fprintf(&b, "- %s (%s)\n", q.Question, q.Owner)
+				} else {
+					fmt.Fprintf(&b, "- %s\n", q.Question)
+				}
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(state.Translations) > 0 {
+		b.WriteString("## Translations\n\n")
+		var languages []string
+		for lang := range state.Translations {
+			languages = append(languages, lang)
+		}
+		sort.Strings(languages)
+		for _, lang := range languages {
+			fmt.Fprintf(&b, "### %s\n\n%s\n\n", lang, strings.TrimSpace(state.Translations[lang].Summary))
+		}
+	}
+
+	fmt.Fprintf(&b, "## Transcript\n\n%s\n", strings.TrimSpace(state.CleanedTranscription))
+
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/r4h4/article-helper/editor"
+)
+
+var update = flag.Bool("update", false, "Update the golden files in testdata")
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		state *State
+	}{
+		{
+			// Without headline, tags, actions or translations
+			name: "minimal",
+			state: &State{
+				Timestamp:            "20261019_140305",
+				Summary:              "- A quick note\n",
+				CleanedTranscription: "Remember to water the plants.\n",
+			},
+		},
+		{
+			name: "full",
+			state: &State{
+				Timestamp:            "20261019_140305",
+				Headline:             "Enterprise Pricing Update",
+				Summary:              "- Prices go up next quarter\n- Existing customers keep their price",
+				CleanedTranscription: "Alice: We raise prices next quarter.\nBob: What about existing customers?",
+				Tags: &editor.TagsResponse{
+					Tags:     []string{"pricing", "enterprise"},
+					Category: "meeting",
+				},
+				Actions: &editor.ActionsResponse{
+					ActionItems: []editor.ActionItem{
+						{Owner: "Alice", Task: "Send the new price list", Due: "Friday"},
+						{Task: "Update the website"},
+					},
+					Decisions:     []editor.Decision{{Decision: "Raise prices next quarter"}},
+					OpenQuestions: []editor.OpenQuestion{{Question: "What about existing customers?", Owner: "Bob"}, {Question: "Which regions?"}},
+				},
+				Translations: map[string]*editor.EditorResponse{
+					"german": {Summary: "- Die Preise steigen im nächsten Quartal"},
+					"french": {Summary: "- Les prix augmentent au prochain trimestre"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderMarkdown(tt.state)
+
+			golden := filepath.Join("testdata", "markdown", tt.name+".md")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(expected) {
+				t.Errorf("Unexpected Markdown, run with -update to accept it:\n%s", got)
+			}
+		})
+	}
+}
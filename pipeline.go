@@ -2,48 +2,266 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/embed"
+	"github.com/r4h4/article-helper/encoder"
+	"github.com/r4h4/article-helper/glossary"
+	"github.com/r4h4/article-helper/library"
 	"github.com/r4h4/article-helper/recorder"
+	"github.com/r4h4/article-helper/recording"
+	"github.com/r4h4/article-helper/transcript"
+	"github.com/r4h4/article-helper/usage"
 	"github.com/r4h4/article-helper/whisper"
 )
 
+// recordingsDir holds one folder per recording, plus the library index
+const recordingsDir = "./recordings"
+
+// defaultBaseURL is the OpenAI API, which OPENAI_BASE_URL overrides
+const defaultBaseURL = "https://api.openai.com/v1"
+
+// State is passed from step to step. Plugins receive it as JSON.
 type State struct {
-	Timestamp            string
-	OutFolder            string
-	OutputFile           string
-	Transcription        string
-	CleanedTranscription string
-	Summary              string
-	Headline             string
+	Timestamp            string                            `json:"timestamp"`
+	OutFolder            string                            `json:"out_folder"`
+	OutputFile           string                            `json:"output_file"`
+	UploadFile           string                            `json:"upload_file,omitempty"`
+	Transcription        string                            `json:"transcription"`
+	RawTranscription     string                            `json:"raw_transcription,omitempty"` // Transcription before the glossary
+	Language             string                            `json:"language"`
+	GlossaryReport       []glossary.Replacement            `json:"glossary_report,omitempty"`
+	Segments             []transcript.Segment              `json:"segments,omitempty"`
+	CleanedTranscription string                            `json:"cleaned_transcription"`
+	Summary              string                            `json:"summary"`
+	Translations         map[string]*editor.EditorResponse `json:"translations,omitempty"`
+	Actions              *editor.ActionsResponse           `json:"actions,omitempty"`
+	Tags                 *editor.TagsResponse              `json:"tags,omitempty"`
+	Headline             string                            `json:"headline"`
+	Slug                 string                            `json:"slug,omitempty"`
+	Headlines            editor.Headlines                  `json:"headlines,omitempty"`
+	Usage                *usage.Tracker                    `json:"-"`
+}
+
+// EditorInput returns the transcript to edit, labeled with speakers if it was diarized
+func (s *State) EditorInput() string {
+	if len(s.Segments) > 0 {
+		return transcript.Format(s.Segments)
+	}
+	return s.Transcription
+}
+
+// Metadata returns the metadata describing the recording
+func (s *State) Metadata() *recording.Metadata {
+	m := &recording.Metadata{
+		Timestamp: s.Timestamp,
+		Headline:  s.Headline,
+		Slug:      s.Slug,
+		Headlines: s.Headlines,
+		AudioFile: s.OutputFile,
+		Language:  s.Language,
+	}
+	for lang := range s.Translations {
+		m.Translations = append(m.Translations, lang)
+	}
+	sort.Strings(m.Translations)
+	if date, err := time.ParseInLocation(recording.TimestampLayout, s.Timestamp, time.Local); err == nil {
+		m.Date = date
+	}
+	if s.Usage != nil {
+		m.Usage = s.Usage.Summary()
+	}
+	if s.Tags != nil {
+		m.Tags = s.Tags.Tags
+		m.Entities = &s.Tags.Entities
+		m.Category = s.Tags.Category
+	}
+	return m
 }
 
+// LibraryEntry returns the entry indexing the recording in the library
+func (s *State) LibraryEntry() *library.Entry {
+	m := s.Metadata()
+	return &library.Entry{
+		ID:         filepath.Base(s.OutFolder),
+		Date:       m.Date,
+		Headline:   m.Headline,
+		Tags:       m.Tags,
+		Category:   m.Category,
+		Summary:    s.Summary,
+		Transcript: s.CleanedTranscription,
+	}
+}
+
+// Field names a part of the state that steps read or write
+type Field string
+
+const (
+	FieldAudio         Field = "audio"         // OutputFile
+	FieldUpload        Field = "upload"        // UploadFile
+	FieldTranscription Field = "transcription" // Transcription and Language
+	FieldSegments      Field = "segments"
+	FieldCleaned       Field = "cleaned_transcription"
+	FieldSummary       Field = "summary"
+	FieldTranslations  Field = "translations"
+	FieldActions       Field = "actions"
+	FieldTags          Field = "tags"
+	FieldHeadline      Field = "headline"
+	FieldSlug          Field = "slug"
+	FieldHeadlines     Field = "headlines" // Candidates in all styles
+	FieldFolder        Field = "folder"    // OutFolder, once named after the recording
+)
+
 type Step interface {
+	// Name identifies the step in errors, progress and the skip_steps config, e.g. "transcribe"
+	Name() string
+	// Requires lists the fields earlier steps must produce
+	Requires() []Field
+	// Produces lists the fields the step sets
+	Produces() []Field
 	Execute(ctx context.Context, state *State) error
 }
 
 type RecordStep struct {
-	OutputFile *string
+	OutputFile      *string
+	Device          string
+	AutoStopSilence time.Duration
+}
+
+type ImportStep struct {
+	Input string
+}
+
+type TrimStep struct {
+	Options recorder.VADOptions
+}
+
+type EncodeStep struct {
+	Format encoder.Format
 }
 
 type TranscribeStep struct {
-	APIKey string
+	Client   Transcriber
+	Language string
+	Glossary *glossary.Glossary
+}
+
+type GlossaryStep struct {
+	Glossary *glossary.Glossary
+}
+
+type DiarizeStep struct {
+	Editor       Editor
+	SpeakerNames map[string]string
 }
 
 type EditStep struct {
-	APIKey string
+	Editor   Editor
+	Glossary *glossary.Glossary
+}
+
+type TranslateStep struct {
+	Editor    Editor
+	Languages []string
+}
+
+type ActionsStep struct {
+	Editor Editor
+}
+
+type TagsStep struct {
+	Editor   Editor
+	Taxonomy []string
+}
+
+type SaveStep struct {
+	Quiet bool // Do not print the results, e.g. when processing many files
 }
 
-type SaveStep struct{}
+type EmbedStep struct {
+	Client *embed.Client
+}
+
+// defaultHeadlineCandidates is the number of headline candidates per style
+const defaultHeadlineCandidates = 3
 
 type HeadlineStep struct {
-	APIKey string
+	Editor     Editor
+	Candidates int // Per style (default: defaultHeadlineCandidates)
 }
 
+// RenameStep names the recording folder after the slug of the headline
+type RenameStep struct{}
+
+func (s *RecordStep) Name() string      { return "record" }
+func (s *RecordStep) Requires() []Field { return nil }
+func (s *RecordStep) Produces() []Field { return []Field{FieldAudio} }
+
+func (s *ImportStep) Name() string      { return "import" }
+func (s *ImportStep) Requires() []Field { return nil }
+func (s *ImportStep) Produces() []Field { return []Field{FieldAudio} }
+
+func (s *TrimStep) Name() string      { return "trim" }
+func (s *TrimStep) Requires() []Field { return []Field{FieldAudio} }
+func (s *TrimStep) Produces() []Field { return []Field{FieldUpload} }
+
+func (s *EncodeStep) Name() string      { return "encode" }
+func (s *EncodeStep) Requires() []Field { return []Field{FieldAudio} }
+func (s *EncodeStep) Produces() []Field { return []Field{FieldUpload} }
+
+func (s *TranscribeStep) Name() string      { return "transcribe" }
+func (s *TranscribeStep) Requires() []Field { return []Field{FieldAudio} }
+func (s *TranscribeStep) Produces() []Field { return []Field{FieldTranscription} }
+
+func (s *GlossaryStep) Name() string      { return "glossary" }
+func (s *GlossaryStep) Requires() []Field { return []Field{FieldTranscription} }
+func (s *GlossaryStep) Produces() []Field { return []Field{FieldTranscription} }
+
+func (s *DiarizeStep) Name() string      { return "diarize" }
+func (s *DiarizeStep) Requires() []Field { return []Field{FieldTranscription} }
+func (s *DiarizeStep) Produces() []Field { return []Field{FieldSegments} }
+
+func (s *EditStep) Name() string      { return "edit" }
+func (s *EditStep) Requires() []Field { return []Field{FieldTranscription} }
+func (s *EditStep) Produces() []Field { return []Field{FieldCleaned, FieldSummary} }
+
+func (s *TranslateStep) Name() string      { return "translate" }
+func (s *TranslateStep) Requires() []Field { return []Field{FieldCleaned, FieldSummary} }
+func (s *TranslateStep) Produces() []Field { return []Field{FieldTranslations} }
+
+func (s *ActionsStep) Name() string      { return "actions" }
+func (s *ActionsStep) Requires() []Field { return []Field{FieldTranscription} }
+func (s *ActionsStep) Produces() []Field { return []Field{FieldActions} }
+
+func (s *TagsStep) Name() string      { return "tags" }
+func (s *TagsStep) Requires() []Field { return []Field{FieldTranscription} }
+func (s *TagsStep) Produces() []Field { return []Field{FieldTags} }
+
+func (s *HeadlineStep) Name() string      { return "headline" }
+func (s *HeadlineStep) Requires() []Field { return []Field{FieldSummary} }
+func (s *HeadlineStep) Produces() []Field { return []Field{FieldHeadline, FieldSlug, FieldHeadlines} }
+
+func (s *RenameStep) Name() string      { return "rename" }
+func (s *RenameStep) Requires() []Field { return []Field{FieldSlug} }
+func (s *RenameStep) Produces() []Field { return []Field{FieldFolder} }
+
+func (s *SaveStep) Name() string      { return "save" }
+func (s *SaveStep) Requires() []Field { return []Field{FieldTranscription, FieldCleaned, FieldSummary} }
+func (s *SaveStep) Produces() []Field { return nil }
+
+func (s *EmbedStep) Name() string      { return "embed" }
+func (s *EmbedStep) Requires() []Field { return []Field{FieldCleaned} }
+func (s *EmbedStep) Produces() []Field { return nil }
+
 func (s *RecordStep) Execute(ctx context.Context, state *State) error {
 	if err := os.MkdirAll(state.OutFolder, 0755); err != nil {
 		return fmt.Errorf("creating output folder: %w", err)
@@ -58,6 +276,9 @@ func (s *RecordStep) Execute(ctx context.Context, state *State) error {
 	audioOptions := recorder.ConfigurableOptions{
 		RecordingsDir: state.OutFolder,
 		AudioFormat:   "wav",
+		Device:        s.Device,
+
+		AutoStopSilence: s.AutoStopSilence,
 	}
 
 	if err := recorder.RecordAudio(ctx, state.OutputFile, audioOptions); err != nil {
@@ -67,29 +288,127 @@ func (s *RecordStep) Execute(ctx context.Context, state *State) error {
 	return nil
 }
 
-func (s *TranscribeStep) Execute(ctx context.Context, state *State) error {
-	config := whisper.Config{
-		APIEndpoint: "https://api.openai.com/v1/audio/transcriptions",
-		APIKey:      s.APIKey,
+func (s *ImportStep) Execute(ctx context.Context, state *State) error {
+	if err := os.MkdirAll(state.OutFolder, 0755); err != nil {
+		return fmt.Errorf("creating output folder: %w", err)
+	}
+
+	src, err := os.Open(s.Input)
+	if err != nil {
+		return fmt.Errorf("opening input: %w", err)
+	}
+	defer src.Close()
+
+	state.OutputFile = fmt.Sprintf("recording_%s%s", state.Timestamp, strings.ToLower(filepath.Ext(s.Input)))
+	dst, err := os.Create(filepath.Join(state.OutFolder, state.OutputFile))
+	if err != nil {
+		return fmt.Errorf("creating recording: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("copying input: %w", err)
 	}
-	client := whisper.NewClient(config)
+	return dst.Close()
+}
 
+// Execute writes the trimmed audio to a separate upload file, keeping the recording as it is
+func (s *TrimStep) Execute(ctx context.Context, state *State) error {
+	state.UploadFile = ""
+	trimmedFile := strings.TrimSuffix(state.OutputFile, filepath.Ext(state.OutputFile)) + ".trimmed.wav"
+	trimmed, err := recorder.TrimSilence(
+		filepath.Join(state.OutFolder, state.OutputFile),
+		filepath.Join(state.OutFolder, trimmedFile),
+		s.Options,
+	)
+	if err != nil {
+		return fmt.Errorf("trimming silence: %w", err)
+	}
+
+	if trimmed > 0 {
+		state.UploadFile = trimmedFile
+		slog.Info("Trimmed silence", "duration", trimmed.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// Execute encodes the trimmed audio, if any, or else the recording
+func (s *EncodeStep) Execute(ctx context.Context, state *State) error {
+	source := state.UploadFile
+	if source == "" {
+		source = state.OutputFile
+	}
+	encoded, err := encoder.Encode(ctx, filepath.Join(state.OutFolder, source), s.Format)
+	if err != nil {
+		return fmt.Errorf("encoding audio: %w", err)
+	}
+
+	state.UploadFile = filepath.Base(encoded)
+	return nil
+}
+
+func (s *TranscribeStep) Execute(ctx context.Context, state *State) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
-	filePath := filepath.Join(state.OutFolder, state.OutputFile)
-	transcription, err := client.TranscribeAudio(ctx, filePath)
+	uploadFile := state.UploadFile
+	if uploadFile == "" {
+		uploadFile = state.OutputFile
+	}
+
+	filePath := filepath.Join(state.OutFolder, uploadFile)
+	opts := whisper.Options{Language: s.Language}
+	if s.Glossary != nil {
+		opts.Prompt = s.Glossary.Prompt()
+	}
+
+	transcription, err := s.Client.Transcribe(ctx, filePath, opts)
 	if err != nil {
 		return fmt.Errorf("transcribing audio: %w", err)
 	}
 
-	state.Transcription = transcription
+	state.Transcription = transcription.Text
+	state.RawTranscription = ""
+	state.Language = transcription.Language
+	state.Usage.AddAudio(whisper.Model, transcription.Duration)
+	return nil
+}
+
+func (s *GlossaryStep) Execute(ctx context.Context, state *State) error {
+	// Keep the text as transcribed, and correct it rather than an earlier correction when resuming
+	if state.RawTranscription == "" {
+		state.RawTranscription = state.Transcription
+	}
+	corrected, report := s.Glossary.Apply(state.RawTranscription)
+	state.Transcription = corrected
+	state.GlossaryReport = report
+
+	for _, r := range report {
+		slog.Info("Applied glossary", "from", r.From, "to", r.To, "count", r.Count)
+	}
+	return nil
+}
+
+func (s *DiarizeStep) Execute(ctx context.Context, state *State) error {
+	result, err := s.Editor.AttributeSpeakers(ctx, state.Transcription)
+	if err != nil {
+		return fmt.Errorf("attributing speakers: %w", err)
+	}
+
+	segments := make([]transcript.Segment, len(result.Segments))
+	for i, seg := range result.Segments {
+		segments[i] = transcript.Segment{Speaker: seg.Speaker, Text: seg.Text}
+	}
+	state.Segments = transcript.Rename(segments, s.SpeakerNames)
 	return nil
 }
 
 func (s *EditStep) Execute(ctx context.Context, state *State) error {
-	ed := editor.NewAIEditor(s.APIKey, "")
-	result, err := ed.EditAndSummarize(state.Transcription)
+	opts := editor.EditOptions{Language: state.Language}
+	if s.Glossary != nil {
+		opts.Glossary = s.Glossary.String()
+	}
+
+	result, err := s.Editor.EditAndSummarize(ctx, state.EditorInput(), opts)
 	if err != nil {
 		return fmt.Errorf("editing and summarizing: %w", err)
 	}
@@ -98,11 +417,71 @@ func (s *EditStep) Execute(ctx context.Context, state *State) error {
 	return nil
 }
 
+func (s *TranslateStep) Execute(ctx context.Context, state *State) error {
+	edited := &editor.EditorResponse{
+		CleanedTranscription: state.CleanedTranscription,
+		Summary:              state.Summary,
+	}
+
+	state.Translations = make(map[string]*editor.EditorResponse, len(s.Languages))
+	for _, lang := range s.Languages {
+		result, err := s.Editor.Translate(ctx, edited, lang)
+		if err != nil {
+			return fmt.Errorf("translating to %s: %w", lang, err)
+		}
+		state.Translations[lang] = result
+	}
+	return nil
+}
+
+func (s *ActionsStep) Execute(ctx context.Context, state *State) error {
+	result, err := s.Editor.ExtractActions(ctx, state.EditorInput())
+	if err != nil {
+		return fmt.Errorf("extracting actions: %w", err)
+	}
+	state.Actions = result
+	return nil
+}
+
+func (s *TagsStep) Execute(ctx context.Context, state *State) error {
+	result, err := s.Editor.ExtractTags(ctx, state.EditorInput(), s.Taxonomy)
+	if err != nil {
+		return fmt.Errorf("extracting tags: %w", err)
+	}
+	state.Tags = result
+	return nil
+}
+
 func (s *SaveStep) Execute(ctx context.Context, state *State) error {
 	files := map[string]string{
 		fmt.Sprintf("transcription_%s.txt", state.Timestamp):         state.Transcription,
 		fmt.Sprintf("cleaned_transcription_%s.txt", state.Timestamp): state.CleanedTranscription,
 		fmt.Sprintf("summary_%s.txt", state.Timestamp):               state.Summary,
+		fmt.Sprintf("notes_%s.md", state.Timestamp):                  renderMarkdown(state),
+	}
+	for lang, t := range state.Translations {
+		files[fmt.Sprintf("cleaned_transcription_%s.%s.txt", state.Timestamp, lang)] = t.CleanedTranscription
+		files[fmt.Sprintf("summary_%s.%s.txt", state.Timestamp, lang)] = t.Summary
+	}
+	if len(state.Segments) > 0 {
+		files[fmt.Sprintf("diarized_transcription_%s.txt", state.Timestamp)] = transcript.Format(state.Segments)
+	}
+	if state.RawTranscription != "" {
+		files[fmt.Sprintf("raw_transcription_%s.txt", state.Timestamp)] = state.RawTranscription
+	}
+	if len(state.GlossaryReport) > 0 {
+		report, err := json.MarshalIndent(state.GlossaryReport, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding glossary report: %w", err)
+		}
+		files["glossary_report.json"] = string(report)
+	}
+	if state.Actions != nil {
+		actions, err := json.MarshalIndent(state.Actions, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding actions: %w", err)
+		}
+		files["actions.json"] = string(actions)
 	}
 
 	for fileName, content := range files {
@@ -112,24 +491,62 @@ func (s *SaveStep) Execute(ctx context.Context, state *State) error {
 		}
 	}
 
-	fmt.Printf("Cleaned Transcription:\n%s\n\n", state.CleanedTranscription)
-	fmt.Printf("Summary:\n%s\n", state.Summary)
+	if err := state.Metadata().Save(state.OutFolder); err != nil {
+		return fmt.Errorf("saving metadata: %w", err)
+	}
+
+	lib, err := library.Open(filepath.Dir(state.OutFolder))
+	if err != nil {
+		return err
+	}
+	if err := lib.Add(state.LibraryEntry()); err != nil {
+		return fmt.Errorf("adding to library: %w", err)
+	}
+
+	if !s.Quiet {
+		fmt.Printf("Cleaned Transcription:\n%s\n\n", state.CleanedTranscription)
+		fmt.Printf("Summary:\n%s\n", state.Summary)
+	}
 
 	return nil
 }
 
+func (s *EmbedStep) Execute(ctx context.Context, state *State) error {
+	store, err := embed.OpenStore(filepath.Dir(state.OutFolder))
+	if err != nil {
+		return err
+	}
+	if err := store.Index(ctx, s.Client, filepath.Base(state.OutFolder), state.CleanedTranscription); err != nil {
+		return fmt.Errorf("embedding transcript: %w", err)
+	}
+
+	// The metadata was saved before, update it with the cost of embedding
+	if err := state.Metadata().Save(state.OutFolder); err != nil {
+		return fmt.Errorf("saving metadata: %w", err)
+	}
+	return nil
+}
+
 func (s *HeadlineStep) Execute(ctx context.Context, state *State) error {
-	ed := editor.NewAIEditor(s.APIKey, "")
-	result, err := ed.CreateHeadline(state.Summary)
+	n := s.Candidates
+	if n <= 0 {
+		n = defaultHeadlineCandidates
+	}
+	result, err := s.Editor.CreateHeadline(ctx, state.Summary, n)
 	if err != nil {
 		return fmt.Errorf("creating headline: %w", err)
 	}
-	state.Headline = result.Headline
+	state.Headline = result.Headlines.Best(editor.HeadlineTitle)
+	state.Slug = result.Slug
+	state.Headlines = result.Headlines
+	return nil
+}
 
-	newFolderName := filepath.Join("./recordings", fmt.Sprintf("%s_%s", state.Timestamp, state.Headline))
+func (s *RenameStep) Execute(ctx context.Context, state *State) error {
+	newFolderName := filepath.Join(filepath.Dir(state.OutFolder), fmt.Sprintf("%s_%s", state.Timestamp, state.Slug))
 	if err := os.Rename(state.OutFolder, newFolderName); err != nil {
 		return fmt.Errorf("renaming folder: %w", err)
 	}
-
+	state.OutFolder = newFolderName
 	return nil
 }
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/r4h4/article-helper/glossary"
+)
+
+func TestGlossaryStep(t *testing.T) {
+	step := &GlossaryStep{Glossary: &glossary.Glossary{Terms: []glossary.Term{{Term: "Acme Corp", Aliases: []string{"Acme"}}}}}
+	state := &State{Transcription: "Acme is great and so is Acme Corp."}
+
+	// Running the step again, e.g. when resuming, corrects the raw text again
+	for range 2 {
+		if err := step.Execute(context.Background(), state); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if state.Transcription != "Acme Corp is great and so is Acme Corp." {
+			t.Errorf("Unexpected transcription %q", state.Transcription)
+		}
+		if state.RawTranscription != "Acme is great and so is Acme Corp." {
+			t.Errorf("Expected the raw transcription to be kept, got %q", state.RawTranscription)
+		}
+		if len(state.GlossaryReport) != 1 || state.GlossaryReport[0].Count != 1 {
+			t.Errorf("Unexpected report %+v", state.GlossaryReport)
+		}
+	}
+}
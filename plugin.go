@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/r4h4/article-helper/config"
+	"github.com/r4h4/article-helper/editor"
+)
+
+// defaultPluginTimeout bounds plugins which do not set a timeout
+const defaultPluginTimeout = time.Minute
+
+// PluginStep runs an executable configured by the user, see config.Plugin
+type PluginStep struct {
+	Plugin  config.Plugin
+	Timeout time.Duration
+	Warn    bool // Log failures and continue instead of failing the run
+}
+
+func newPluginStep(p config.Plugin) (*PluginStep, error) {
+	if p.Name == "" || len(p.Command) == 0 {
+		return nil, fmt.Errorf("plugins need a name and a command")
+	}
+	if p.Before != "" && p.After != "" {
+		return nil, fmt.Errorf("plugin %s: set either before or after", p.Name)
+	}
+
+	// Relative paths are relative to the working directory, not the recording folder
+	if strings.ContainsRune(p.Command[0], filepath.Separator) {
+		path, err := filepath.Abs(p.Command[0])
+		if err != nil {
+			return nil, err
+		}
+		p.Command = append([]string{path}, p.Command[1:]...)
+	}
+
+	step := &PluginStep{Plugin: p, Timeout: defaultPluginTimeout}
+	if p.Timeout != "" {
+		timeout, err := time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: invalid timeout: %w", p.Name, err)
+		}
+		step.Timeout = timeout
+	}
+	for _, field := range p.Produces {
+		if _, ok := pluginFields[Field(field)]; !ok {
+			return nil, fmt.Errorf("plugin %s: cannot produce %s", p.Name, field)
+		}
+	}
+	switch p.OnError {
+	case "", "abort":
+	case "warn":
+		step.Warn = true
+	default:
+		return nil, fmt.Errorf("plugin %s: on_error must be abort or warn, not %q", p.Name, p.OnError)
+	}
+	return step, nil
+}
+
+func (s *PluginStep) Name() string      { return s.Plugin.Name }
+func (s *PluginStep) Requires() []Field { return toFields(s.Plugin.Requires) }
+func (s *PluginStep) Produces() []Field { return toFields(s.Plugin.Produces) }
+
+func (s *PluginStep) Execute(ctx context.Context, state *State) error {
+	err := s.run(ctx, state)
+	if err != nil && s.Warn {
+		slog.Warn("Plugin failed, continuing", "plugin", s.Plugin.Name, "err", err)
+		return nil
+	}
+	return err
+}
+
+func (s *PluginStep) run(ctx context.Context, state *State) error {
+	input, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.Plugin.Command[0], s.Plugin.Command[1:]...)
+	cmd.Dir = state.OutFolder
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// Do not wait for children which inherited the output, once the plugin is killed
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s", s.Timeout)
+		}
+		return err
+	}
+	return applyChanges(state, stdout.Bytes(), s.Produces())
+}
+
+// pluginFields copy the fields plugins may produce. The timestamp and folder
+// are managed by the pipeline, and file names and the slug are sanitized, as
+// they become paths in the recording folder.
+var pluginFields = map[Field]func(dst, src *State){
+	FieldAudio:  func(dst, src *State) { dst.OutputFile = fileName(src.OutputFile) },
+	FieldUpload: func(dst, src *State) { dst.UploadFile = fileName(src.UploadFile) },
+	FieldTranscription: func(dst, src *State) {
+		dst.Transcription, dst.Language = src.Transcription, src.Language
+	},
+	FieldSegments:     func(dst, src *State) { dst.Segments = src.Segments },
+	FieldCleaned:      func(dst, src *State) { dst.CleanedTranscription = src.CleanedTranscription },
+	FieldSummary:      func(dst, src *State) { dst.Summary = src.Summary },
+	FieldTranslations: func(dst, src *State) { dst.Translations = src.Translations },
+	FieldActions:      func(dst, src *State) { dst.Actions = src.Actions },
+	FieldTags:         func(dst, src *State) { dst.Tags = src.Tags },
+	FieldHeadline:     func(dst, src *State) { dst.Headline = src.Headline },
+	FieldSlug:         func(dst, src *State) { dst.Slug = editor.Slugify(src.Slug) },
+	FieldHeadlines:    func(dst, src *State) { dst.Headlines = src.Headlines },
+}
+
+// applyChanges sets the fields in the JSON object printed by a plugin, of
+// those it declares to produce
+func applyChanges(state *State, output []byte, produces []Field) error {
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+
+	changed := *state
+	if err := json.Unmarshal(output, &changed); err != nil {
+		return fmt.Errorf("parsing output: %w", err)
+	}
+	for _, field := range produces {
+		pluginFields[field](state, &changed)
+	}
+	return nil
+}
+
+// fileName strips directories from a file name, keeping it empty if unset
+func fileName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return filepath.Base(name)
+}
+
+// insertPlugin adds a plugin step before or after the named step, or at the end
+func insertPlugin(pipeline []Step, plugin *PluginStep) ([]Step, error) {
+	name := plugin.Name()
+	if slices.ContainsFunc(pipeline, func(step Step) bool { return step.Name() == name }) {
+		return nil, fmt.Errorf("plugin %s: a step with this name already exists", name)
+	}
+
+	anchor := plugin.Plugin.Before + plugin.Plugin.After
+	if anchor == "" {
+		return append(pipeline, plugin), nil
+	}
+	i := slices.IndexFunc(pipeline, func(step Step) bool { return step.Name() == anchor })
+	if i < 0 {
+		return nil, fmt.Errorf("plugin %s: no step %s in the pipeline", name, anchor)
+	}
+	if plugin.Plugin.After != "" {
+		i++
+	}
+	return slices.Insert(pipeline, i, Step(plugin)), nil
+}
+
+func toFields(names []string) []Field {
+	fields := make([]Field, len(names))
+	for i, name := range names {
+		fields[i] = Field(name)
+	}
+	return fields
+}
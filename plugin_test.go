@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r4h4/article-helper/config"
+)
+
+// writePlugin writes a shell script plugin and returns its path
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runPlugin(t *testing.T, p config.Plugin, state *State) error {
+	t.Helper()
+	step, err := newPluginStep(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return step.Execute(context.Background(), state)
+}
+
+func TestPluginStep(t *testing.T) {
+	folder := t.TempDir()
+	state := &State{Timestamp: "20240102_150405", OutFolder: folder, Transcription: "Helo world", Summary: "Greeting"}
+
+	plugin := writePlugin(t, `cat > state.json
+echo '{"transcription": "Hello world", "summary": "Changed", "out_folder": "/tmp"}'`)
+	p := config.Plugin{Name: "spellcheck", Command: []string{plugin}, Produces: []string{"transcription"}}
+	if err := runPlugin(t, p, state); err != nil {
+		t.Fatalf("Plugin failed: %v", err)
+	}
+
+	// Fields the plugin does not produce are ignored
+	if state.Transcription != "Hello world" || state.Summary != "Greeting" || state.OutFolder != folder {
+		t.Errorf("Expected only the transcription to change, got %+v", state)
+	}
+	input, err := os.ReadFile(filepath.Join(folder, "state.json"))
+	if err != nil || !strings.Contains(string(input), `"transcription":"Helo world"`) {
+		t.Errorf("Expected the state on stdin in the recording folder, got %s (%v)", input, err)
+	}
+}
+
+func TestPluginSanitizesPaths(t *testing.T) {
+	state := &State{OutFolder: t.TempDir(), OutputFile: "recording.wav", Slug: "memo"}
+
+	plugin := writePlugin(t, `echo '{"headline": "Escape", "slug": "../../escape", "output_file": "../../etc/passwd"}'`)
+	p := config.Plugin{Name: "rename", Command: []string{plugin}, Produces: []string{"headline", "slug", "audio"}}
+	if err := runPlugin(t, p, state); err != nil {
+		t.Fatalf("Plugin failed: %v", err)
+	}
+
+	if state.Headline != "Escape" || state.Slug != "escape" || state.OutputFile != "passwd" {
+		t.Errorf("Expected the slug and file name to stay in the folder, got %+v", state)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin config.Plugin
+		err    string
+	}{
+		{name: "ExitStatus", plugin: config.Plugin{Command: []string{writePlugin(t, "exit 3")}}, err: "exit status 3"},
+		{name: "InvalidOutput", plugin: config.Plugin{Command: []string{writePlugin(t, "echo done")}}, err: "parsing output"},
+		{name: "Timeout", plugin: config.Plugin{Command: []string{writePlugin(t, "exec sleep 10")}, Timeout: "100ms"}, err: "timed out after 100ms"},
+		{name: "Warn", plugin: config.Plugin{Command: []string{writePlugin(t, "exit 1")}, OnError: "warn"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Name = "test"
+			start := time.Now()
+			err := runPlugin(t, tt.plugin, &State{OutFolder: t.TempDir()})
+			if tt.err == "" && err != nil {
+				t.Errorf("Expected the failure to be ignored, got %v", err)
+			} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("Expected error %q, got %v", tt.err, err)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Errorf("Plugin was not stopped after its timeout")
+			}
+		})
+	}
+}
+
+func TestPluginOrder(t *testing.T) {
+	opts := pipelineOptions{
+		Input: "memo.mp3",
+		Plugins: []config.Plugin{
+			{Name: "notion", Command: []string{"push"}},
+			{Name: "spellcheck", Command: []string{"check"}, After: "transcribe", Requires: []string{"transcription"}},
+			{Name: "backup", Command: []string{"backup"}, Before: "save"},
+		},
+	}
+	pipeline, err := newPipeline(opts, fakeClients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"import", "transcribe", "spellcheck", "edit", "headline", "rename", "backup", "save", "notion"}
+	if names := stepNames(pipeline); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected steps %v, got %v", expected, names)
+	}
+
+	for _, tt := range []struct {
+		plugin config.Plugin
+		err    string
+	}{
+		{config.Plugin{Name: "late", Command: []string{"x"}, Before: "transcribe", Requires: []string{"summary"}}, "late requires summary, which no earlier step produces"},
+		{config.Plugin{Name: "edit", Command: []string{"x"}}, "plugin edit: a step with this name already exists"},
+		{config.Plugin{Name: "x", Command: []string{"x"}, After: "tags"}, "plugin x: no step tags in the pipeline"},
+		{config.Plugin{Name: "x", Command: []string{"x"}, OnError: "retry"}, `plugin x: on_error must be abort or warn, not "retry"`},
+		{config.Plugin{Name: "x", Command: []string{"x"}, Produces: []string{"folder"}}, "plugin x: cannot produce folder"},
+	} {
+		opts.Plugins = []config.Plugin{tt.plugin}
+		if _, err := newPipeline(opts, fakeClients); err == nil || err.Error() != tt.err {
+			t.Errorf("Expected error %q, got %v", tt.err, err)
+		}
+	}
+}
@@ -1,18 +1,47 @@
 package recorder
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
+	"time"
 )
 
-func createRecordCommand(fullPath, format string) (*exec.Cmd, error) {
-	if runtime.GOOS == "windows" {
-		return exec.Command("sox", "-d", "-t", "waveaudio", fullPath), nil
+// stopTimeout is how long sox may take to flush its output once asked to stop,
+// before it is killed
+const stopTimeout = 5 * time.Second
+
+// createRecordCommand starts sox capturing from the input device and writing
+// raw 16-bit signed PCM to stdout, so the stream can be inspected before it is
+// saved. Cancelling ctx stops the recording.
+func createRecordCommand(ctx context.Context, opts ConfigurableOptions) (*exec.Cmd, error) {
+	args := []string{"-q"}
+	switch {
+	case opts.Device != "":
+		args = append(args, "-t", inputDriver(), opts.Device)
+	case runtime.GOOS == "windows":
+		args = append(args, "-t", "waveaudio", "default")
+	default:
+		args = append(args, "-d")
 	}
-	return exec.Command("sox", "-d", "-t", format, fullPath), nil
+	args = append(args,
+		"-t", "raw",
+		"-b", strconv.Itoa(bitsPerSample),
+		"-e", "signed-integer",
+		"-L",
+		"-c", strconv.Itoa(opts.Channels),
+		"-r", strconv.Itoa(opts.SampleRate),
+		"-",
+	)
+	cmd := exec.CommandContext(ctx, "sox", args...)
+	cmd.Cancel = func() error { return stopRecording(cmd) }
+	cmd.WaitDelay = stopTimeout
+	return cmd, nil
 }
 
+// stopRecording asks sox to finish writing, where the platform allows it
 func stopRecording(cmd *exec.Cmd) error {
 	if runtime.GOOS == "windows" {
 		return cmd.Process.Kill()
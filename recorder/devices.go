@@ -0,0 +1,150 @@
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Device is an audio capture device that can be passed to sox
+type Device struct {
+	Name        string // Identifier understood by sox for the device's driver
+	Description string // Human-readable name, if the system provides one
+	Driver      string // sox input type, e.g. pulseaudio, alsa, coreaudio or waveaudio
+}
+
+func (d Device) String() string {
+	if d.Description == "" || d.Description == d.Name {
+		return d.Name
+	}
+	return fmt.Sprintf("%s (%s)", d.Name, d.Description)
+}
+
+var (
+	avfoundationDevice = regexp.MustCompile(`\]\s+\[(\d+)\]\s+(.+)$`)
+	dshowDevice        = regexp.MustCompile(`"([^"]+)"\s+\(audio\)`)
+)
+
+// ListDevices returns the capture devices available on this system
+func ListDevices() ([]Device, error) {
+	switch runtime.GOOS {
+	case "linux":
+		if _, err := exec.LookPath("pactl"); err == nil {
+			return listPulseSources()
+		}
+		return listALSADevices()
+	case "darwin":
+		return listFFmpegDevices([]string{"-f", "avfoundation", "-list_devices", "true", "-i", ""}, "coreaudio")
+	case "windows":
+		return listFFmpegDevices([]string{"-f", "dshow", "-list_devices", "true", "-i", "dummy"}, "waveaudio")
+	default:
+		return nil, fmt.Errorf("listing devices is not supported on %s", runtime.GOOS)
+	}
+}
+
+// inputDriver returns the sox input type used for named devices on this system
+func inputDriver() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "coreaudio"
+	case "windows":
+		return "waveaudio"
+	default:
+		if _, err := exec.LookPath("pactl"); err == nil {
+			return "pulseaudio"
+		}
+		return "alsa"
+	}
+}
+
+func listPulseSources() ([]Device, error) {
+	out, err := exec.Command("pactl", "list", "short", "sources").Output()
+	if err != nil {
+		return nil, fmt.Errorf("running pactl: %w", err)
+	}
+	return parsePulseSources(out)
+}
+
+// parsePulseSources parses `pactl list short sources`, skipping monitors of output sinks
+func parsePulseSources(out []byte) ([]Device, error) {
+	var devices []Device
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 || strings.HasSuffix(fields[1], ".monitor") {
+			continue
+		}
+		devices = append(devices, Device{Name: fields[1], Driver: "pulseaudio"})
+	}
+	return devices, scanner.Err()
+}
+
+func listALSADevices() ([]Device, error) {
+	out, err := exec.Command("arecord", "-L").Output()
+	if err != nil {
+		return nil, fmt.Errorf("running arecord: %w", err)
+	}
+	return parseALSADevices(out)
+}
+
+// parseALSADevices parses `arecord -L`, where names are unindented and followed
+// by indented description lines
+func parseALSADevices(out []byte) ([]Device, error) {
+	var devices []Device
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" || line == "null":
+		case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+			devices = append(devices, Device{Name: line, Driver: "alsa"})
+		case len(devices) > 0 && devices[len(devices)-1].Description == "":
+			devices[len(devices)-1].Description = strings.TrimSpace(line)
+		}
+	}
+	return devices, scanner.Err()
+}
+
+// listFFmpegDevices uses ffmpeg's device listing, which is printed to stderr
+// and always ends in an error because no input is opened
+func listFFmpegDevices(args []string, driver string) ([]Device, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("listing devices requires ffmpeg: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("ffmpeg", append([]string{"-hide_banner"}, args...)...)
+	cmd.Stderr = &stderr
+	_ = cmd.Run()
+	return parseFFmpegDevices(stderr.Bytes(), driver)
+}
+
+// parseFFmpegDevices parses the device listing of ffmpeg's avfoundation
+// (coreaudio) or dshow (waveaudio) input
+func parseFFmpegDevices(out []byte, driver string) ([]Device, error) {
+	var devices []Device
+	inAudio := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "audio devices:"):
+			inAudio = true
+		case strings.Contains(line, "video devices:"):
+			inAudio = false
+		case driver == "waveaudio":
+			if m := dshowDevice.FindStringSubmatch(line); m != nil {
+				devices = append(devices, Device{Name: m[1], Driver: driver})
+			}
+		case inAudio:
+			if m := avfoundationDevice.FindStringSubmatch(line); m != nil {
+				devices = append(devices, Device{Name: m[2], Driver: driver})
+			}
+		}
+	}
+	return devices, scanner.Err()
+}
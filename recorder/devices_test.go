@@ -0,0 +1,115 @@
+package recorder
+
+import (
+	"reflect"
+	"testing"
+)
+
+const pactlFixture = `0	alsa_output.pci-0000_00_1f.3.analog-stereo.monitor	module-alsa-card.c	s16le 2ch 44100Hz	SUSPENDED
+1	alsa_input.pci-0000_00_1f.3.analog-stereo	module-alsa-card.c	s16le 2ch 44100Hz	RUNNING
+2	bluez_input.00_11_22_33_44_55.0	module-bluez5-device.c	float32le 1ch 16000Hz	SUSPENDED
+`
+
+const arecordFixture = `null
+    Discard all samples (playback) or generate zero samples (capture)
+default
+    Playback/recording through the PulseAudio sound server
+sysdefault:CARD=PCH
+    HDA Intel PCH, ALC3246 Analog
+    Default Audio Device
+hw:CARD=PCH,DEV=0
+    HDA Intel PCH, ALC3246 Analog
+    Direct hardware device without any conversions
+`
+
+const avfoundationFixture = `[AVFoundation indev @ 0x7f8b1c004a00] AVFoundation video devices:
+[AVFoundation indev @ 0x7f8b1c004a00] [0] FaceTime HD Camera
+[AVFoundation indev @ 0x7f8b1c004a00] [1] Capture screen 0
+[AVFoundation indev @ 0x7f8b1c004a00] AVFoundation audio devices:
+[AVFoundation indev @ 0x7f8b1c004a00] [0] MacBook Pro Microphone
+[AVFoundation indev @ 0x7f8b1c004a00] [1] ZoomAudioDevice
+: Input/output error
+`
+
+const dshowFixture = `[dshow @ 000001f2c3a5e940] "Integrated Webcam" (video)
+[dshow @ 000001f2c3a5e940]   Alternative name "@device_pnp_\\?\usb#vid_0c45&pid_6723&mi_00"
+[dshow @ 000001f2c3a5e940] "Microphone Array (Realtek(R) Audio)" (audio)
+[dshow @ 000001f2c3a5e940]   Alternative name "@device_cm_{33D9A762-90C8-11D0-BD43-00A0C911CE86}\wave_{5A3F8C2B}"
+[dshow @ 000001f2c3a5e940] "Headset Microphone (Jabra EVOLVE 20 MS)" (audio)
+dummy: Immediate exit requested
+`
+
+func TestParseDevices(t *testing.T) {
+	tests := []struct {
+		name     string
+		parse    func([]byte) ([]Device, error)
+		output   string
+		expected []Device
+	}{
+		{
+			name:   "PulseAudio",
+			parse:  parsePulseSources,
+			output: pactlFixture,
+			expected: []Device{
+				{Name: "alsa_input.pci-0000_00_1f.3.analog-stereo", Driver: "pulseaudio"},
+				{Name: "bluez_input.00_11_22_33_44_55.0", Driver: "pulseaudio"},
+			},
+		},
+		{
+			name:   "ALSA",
+			parse:  parseALSADevices,
+			output: arecordFixture,
+			expected: []Device{
+				{Name: "default", Description: "Playback/recording through the PulseAudio sound server", Driver: "alsa"},
+				{Name: "sysdefault:CARD=PCH", Description: "HDA Intel PCH, ALC3246 Analog", Driver: "alsa"},
+				{Name: "hw:CARD=PCH,DEV=0", Description: "HDA Intel PCH, ALC3246 Analog", Driver: "alsa"},
+			},
+		},
+		{
+			name:   "AVFoundation",
+			parse:  func(out []byte) ([]Device, error) { return parseFFmpegDevices(out, "coreaudio") },
+			output: avfoundationFixture,
+			expected: []Device{
+				{Name: "MacBook Pro Microphone", Driver: "coreaudio"},
+				{Name: "ZoomAudioDevice", Driver: "coreaudio"},
+			},
+		},
+		{
+			name:   "DirectShow",
+			parse:  func(out []byte) ([]Device, error) { return parseFFmpegDevices(out, "waveaudio") },
+			output: dshowFixture,
+			expected: []Device{
+				{Name: "Microphone Array (Realtek(R) Audio)", Driver: "waveaudio"},
+				{Name: "Headset Microphone (Jabra EVOLVE 20 MS)", Driver: "waveaudio"},
+			},
+		},
+		{name: "Empty", parse: parsePulseSources, output: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devices, err := tt.parse([]byte(tt.output))
+			if err != nil {
+				t.Fatalf("Parsing failed: %v", err)
+			}
+			if !reflect.DeepEqual(devices, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, devices)
+			}
+		})
+	}
+}
+
+func TestDeviceString(t *testing.T) {
+	for _, tt := range []struct {
+		device   Device
+		expected string
+	}{
+		{Device{Name: "default"}, "default"},
+		{Device{Name: "default", Description: "default"}, "default"},
+		{Device{Name: "hw:CARD=PCH,DEV=0", Description: "HDA Intel PCH"}, "hw:CARD=PCH,DEV=0 (HDA Intel PCH)"},
+	} {
+		if got := tt.device.String(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
@@ -2,14 +2,14 @@ package recorder
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 
 	"github.com/eiannone/keyboard"
 )
 
 func listenForEscKey(ctx context.Context, keyChan chan<- keyboard.Key) {
 	if err := keyboard.Open(); err != nil {
-		fmt.Println("Failed to open keyboard:", err)
+		slog.Error("Opening keyboard failed", "err", err)
 		return
 	}
 	defer keyboard.Close()
@@ -17,10 +17,11 @@ func listenForEscKey(ctx context.Context, keyChan chan<- keyboard.Key) {
 	for {
 		char, key, err := keyboard.GetKey()
 		if err != nil {
-			fmt.Println("Error reading keyboard:", err)
+			slog.Error("Reading keyboard failed", "err", err)
 			return
 		}
-		if key == keyboard.KeyEsc || char == 'q' || char == 'Q' {
+		// The terminal is in raw mode, so Ctrl-C arrives as a key too
+		if key == keyboard.KeyEsc || key == keyboard.KeyCtrlC || char == 'q' || char == 'Q' {
 			keyChan <- key
 			return
 		}
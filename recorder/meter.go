@@ -0,0 +1,79 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// Levels below this are treated as silence
+	silenceThresholdDB = -50.0
+	// Floor of the level scale, used for digital silence
+	minLevelDB = -90.0
+)
+
+// levelMeter tracks the input level of the captured PCM stream
+type levelMeter struct {
+	mu        sync.Mutex
+	level     float64
+	lastSound time.Time
+}
+
+// meterReading is a point-in-time view of the meter
+type meterReading struct {
+	Level     float64       // RMS level of the most recent chunk in dBFS
+	SilentFor time.Duration // How long the input has stayed below the silence threshold
+}
+
+func newLevelMeter(now time.Time) *levelMeter {
+	return &levelMeter{
+		level:     minLevelDB,
+		lastSound: now,
+	}
+}
+
+// Update feeds a chunk of 16-bit little-endian PCM into the meter
+func (m *levelMeter) Update(pcm []byte, now time.Time) {
+	level := rmsDBFS(pcm)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.level = level
+	if level > silenceThresholdDB {
+		m.lastSound = now
+	}
+}
+
+// Read returns the current level and silence duration
+func (m *levelMeter) Read(now time.Time) meterReading {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return meterReading{
+		Level:     m.level,
+		SilentFor: now.Sub(m.lastSound),
+	}
+}
+
+// rmsDBFS returns the RMS level of 16-bit little-endian PCM relative to full scale
+func rmsDBFS(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return minLevelDB
+	}
+
+	var sum float64
+	for i := 0; i < n; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / math.MaxInt16
+		sum += s * s
+	}
+
+	rms := math.Sqrt(sum / float64(n))
+	if rms == 0 {
+		return minLevelDB
+	}
+	return math.Max(20*math.Log10(rms), minLevelDB)
+}
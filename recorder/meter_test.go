@@ -0,0 +1,74 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// samplesPCM encodes samples as 16-bit little-endian PCM
+func samplesPCM(samples ...int16) []byte {
+	var pcm []byte
+	for _, s := range samples {
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(s))
+	}
+	return pcm
+}
+
+func TestRMSDBFS(t *testing.T) {
+	tests := []struct {
+		name     string
+		pcm      []byte
+		expected float64
+	}{
+		{name: "NoSamples", pcm: nil, expected: minLevelDB},
+		{name: "PartialSample", pcm: []byte{0x7f}, expected: minLevelDB},
+		{name: "DigitalSilence", pcm: samplesPCM(0, 0, 0, 0), expected: minLevelDB},
+		{name: "BelowFloor", pcm: samplesPCM(1, -1), expected: minLevelDB},
+		{name: "FullScale", pcm: samplesPCM(math.MaxInt16, -math.MaxInt16), expected: 0},
+		{name: "HalfScale", pcm: samplesPCM(math.MaxInt16/2, -math.MaxInt16/2), expected: -6.02},
+		{name: "HalfScaleSine", pcm: testPCM(part(true, time.Second)), expected: -9.03},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rmsDBFS(tt.pcm); math.Abs(got-tt.expected) > 0.01 {
+				t.Errorf("Expected %.2f dBFS, got %.2f", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLevelMeter(t *testing.T) {
+	start := time.Now()
+	loud := testPCM(part(true, 100*time.Millisecond))
+	quiet := testPCM(part(false, 100*time.Millisecond))
+
+	m := newLevelMeter(start)
+	steps := []struct {
+		pcm       []byte
+		at        time.Duration
+		silentFor time.Duration
+	}{
+		// Silence is counted from the start until there is sound
+		{pcm: quiet, at: 2 * time.Second, silentFor: 2 * time.Second},
+		{pcm: loud, at: 3 * time.Second, silentFor: 0},
+		{pcm: quiet, at: 4 * time.Second, silentFor: time.Second},
+		{pcm: quiet, at: 6 * time.Second, silentFor: 3 * time.Second},
+		// Sound above the threshold resets the counter
+		{pcm: loud, at: 7 * time.Second, silentFor: 0},
+		{pcm: quiet, at: 8 * time.Second, silentFor: time.Second},
+	}
+
+	for _, step := range steps {
+		m.Update(step.pcm, start.Add(step.at))
+		reading := m.Read(start.Add(step.at))
+		if reading.SilentFor != step.silentFor {
+			t.Errorf("Expected %s of silence at %s, got %s", step.silentFor, step.at, reading.SilentFor)
+		}
+		if want := rmsDBFS(step.pcm); reading.Level != want {
+			t.Errorf("Expected a level of %.2f at %s, got %.2f", want, step.at, reading.Level)
+		}
+	}
+}
@@ -1,83 +1,248 @@
 package recorder
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"os/exec"
-	"os/signal"
 	"path/filepath"
-	"runtime"
-	"syscall"
+	"strings"
+	"time"
 
 	"github.com/eiannone/keyboard"
 )
 
+const (
+	defaultSampleRate     = 16000
+	defaultChannels       = 1
+	defaultSilenceWarning = 5 * time.Second
+
+	// How often the status line is redrawn
+	statusInterval = 200 * time.Millisecond
+	// Duration of PCM read from sox per chunk
+	chunkDuration = 100 * time.Millisecond
+)
+
 // ConfigurableOptions allows for easier extension and configuration
 type ConfigurableOptions struct {
 	RecordingsDir string
 	AudioFormat   string
+
+	// Device to capture from, as listed by ListDevices (default: system default device)
+	Device string
+
+	// SampleRate and Channels of the captured stream (default: 16 kHz mono, as expected by Whisper)
+	SampleRate int
+	Channels   int
+
+	// SilenceWarning is how long the input may stay silent before the status line warns about it.
+	// A negative value disables the warning.
+	SilenceWarning time.Duration
+
+	// AutoStopSilence stops the recording once speech has been heard and is followed
+	// by this much silence, as judged by the VAD. Zero disables auto-stop.
+	AutoStopSilence time.Duration
+	VAD             VADOptions
+
+	// Status receives the live status line (default: os.Stdout)
+	Status io.Writer
+}
+
+func (opts ConfigurableOptions) withDefaults() ConfigurableOptions {
+	if opts.AudioFormat == "" {
+		opts.AudioFormat = "wav"
+	}
+	if opts.SampleRate == 0 {
+		opts.SampleRate = defaultSampleRate
+	}
+	if opts.Channels == 0 {
+		opts.Channels = defaultChannels
+	}
+	if opts.SilenceWarning == 0 {
+		opts.SilenceWarning = defaultSilenceWarning
+	}
+	if opts.Status == nil {
+		opts.Status = os.Stdout
+	}
+	return opts
 }
 
-// RecordAudio records audio in the terminal and saves the output to a file
+// RecordAudio records audio in the terminal and saves the output to a file.
+// Pressing Ctrl-C saves the recording and returns context.Canceled, so the
+// whole run is interrupted, not just the recording.
 func RecordAudio(ctx context.Context, outputFile string, opts ConfigurableOptions) error {
+	opts = opts.withDefaults()
+
+	if opts.AudioFormat != "wav" {
+		return fmt.Errorf("unsupported audio format: %s", opts.AudioFormat)
+	}
+
 	if err := ensureDir(opts.RecordingsDir); err != nil {
 		return fmt.Errorf("failed to create recordings directory: %w", err)
 	}
 
 	fullPath := filepath.Join(opts.RecordingsDir, outputFile)
 
-	cmd, err := createRecordCommand(fullPath, opts.AudioFormat)
+	// Cancelling the command stops sox; it is independent of ctx so that every
+	// way of stopping drains the captured audio below
+	cmdCtx, stopCmd := context.WithCancel(context.Background())
+	defer stopCmd()
+	cmd, err := createRecordCommand(cmdCtx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to create record command: %w", err)
 	}
 
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open recorder output: %w", err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := newWAVWriter(fullPath, opts.SampleRate, opts.Channels)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
 	// Create a cancellable context
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	if err := cmd.Start(); err != nil {
+		out.Close()
 		return fmt.Errorf("failed to start recording: %w", err)
 	}
 
-	fmt.Println("Recording started. Press ESC to stop...")
+	started := time.Now()
+	meter := newLevelMeter(started)
+	captureDone := make(chan error, 1)
+	autoStop := make(chan struct{}, 1)
+	go func() {
+		captureDone <- capture(stdout, out, meter, autoStop, opts)
+	}()
 
-	// Setup signal handling
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	slog.Info("Recording started, press ESC to stop")
+	if opts.AutoStopSilence > 0 {
+		slog.Info("Recording stops automatically after silence", "silence", opts.AutoStopSilence)
+	}
 
 	// Setup keyboard listening
 	keyChan := make(chan keyboard.Key, 1)
 	go listenForEscKey(ctx, keyChan)
 
-	// Wait for stop signal
-	select {
-	case <-signalChan:
-		fmt.Println("\nReceived interrupt signal. Stopping recording...")
-	case key := <-keyChan:
-		if key == keyboard.KeyEsc {
-			fmt.Println("ESC pressed. Stopping recording...")
+	status := newStatusLine(opts.Status)
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	// Wait for stop signal, refreshing the status line in the meantime
+	var captureErr error
+	interrupted := false
+loop:
+	for {
+		select {
+		case now := <-ticker.C:
+			status.Render(now.Sub(started), out.Size(), meter.Read(now), opts.SilenceWarning)
+			continue
+		case key := <-keyChan:
+			status.Clear()
+			switch key {
+			case keyboard.KeyEsc:
+				slog.Info("ESC pressed, stopping recording")
+			case keyboard.KeyCtrlC:
+				slog.Info("Interrupted, stopping recording")
+				interrupted = true
+			}
+		case <-ctx.Done():
+			status.Clear()
+			slog.Info("Interrupted, stopping recording")
+		case <-autoStop:
+			status.Clear()
+			slog.Info("No speech, stopping recording", "silence", opts.AutoStopSilence)
+		case captureErr = <-captureDone:
+			status.Clear()
+			captureDone = nil
+			if captureErr == nil {
+				captureErr = errors.New("recorder exited unexpectedly")
+			}
 		}
-	case <-ctx.Done():
-		fmt.Println("Context cancelled. Stopping recording...")
+		break loop
+	}
+
+	// Stop the recording and drain what sox has already captured
+	if captureDone != nil {
+		stopCmd()
+		captureErr = <-captureDone
+	}
+
+	waitErr := cmd.Wait()
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+
+	if captureErr != nil {
+		return fmt.Errorf("error during recording: %w%s", captureErr, soxOutput(&stderr))
+	}
+
+	// Once stopped, the exit status only tells how sox was stopped, e.g. killed on Windows
+	if waitErr != nil && cmdCtx.Err() == nil {
+		return fmt.Errorf("error during recording: %w%s", waitErr, soxOutput(&stderr))
 	}
 
-	// Stop the recording
-	if err := stopRecording(cmd); err != nil {
-		fmt.Printf("Error stopping recording: %v\n", err)
+	slog.Info("Audio recorded", "file", fullPath, "duration", formatElapsed(time.Since(started)), "size", formatSize(out.Size()))
+	if interrupted {
+		return context.Canceled
 	}
+	return nil
+}
+
+// capture copies PCM from the recorder into the output file, feeding each chunk
+// through the level meter and, if auto-stop is enabled, the VAD. It returns nil
+// once the recorder closes its output.
+func capture(r io.Reader, out *wavWriter, meter *levelMeter, autoStop chan<- struct{}, opts ConfigurableOptions) error {
+	frameSize := opts.Channels * bitsPerSample / 8
+	chunkFrames := int(chunkDuration.Seconds() * float64(opts.SampleRate))
+	buf := make([]byte, chunkFrames*frameSize)
+
+	var vad *VAD
+	if opts.AutoStopSilence > 0 {
+		vad = NewVAD(opts.VAD, opts.SampleRate, opts.Channels)
+	}
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			chunk := buf[:n-n%frameSize]
+			if _, werr := out.Write(chunk); werr != nil {
+				return fmt.Errorf("writing audio: %w", werr)
+			}
+			meter.Update(chunk, time.Now())
 
-	if err := cmd.Wait(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			// On Unix-like systems, exit status 1 might be normal for interrupted processes
-			if exitErr.ExitCode() != 1 || runtime.GOOS == "windows" {
-				return fmt.Errorf("error during recording: %w", err)
+			if vad != nil {
+				vad.Feed(chunk)
+				if vad.HeardSpeech() && vad.Silence() >= opts.AutoStopSilence {
+					select {
+					case autoStop <- struct{}{}:
+					default:
+					}
+				}
 			}
-		} else {
-			return fmt.Errorf("error during recording: %w", err)
+		}
+
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			return nil
+		case err != nil:
+			return fmt.Errorf("reading audio: %w", err)
 		}
 	}
+}
 
-	fmt.Printf("Audio recorded successfully: %s\n", fullPath)
-	return nil
+func soxOutput(stderr *bytes.Buffer) string {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return ": " + msg
+	}
+	return ""
 }
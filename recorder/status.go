@@ -0,0 +1,61 @@
+package recorder
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	meterWidth   = 20
+	meterFloorDB = -60.0
+)
+
+// statusLine renders a single, continuously overwritten line in the terminal
+type statusLine struct {
+	w io.Writer
+}
+
+func newStatusLine(w io.Writer) *statusLine {
+	return &statusLine{w: w}
+}
+
+// Render redraws the line with the current recording status
+func (s *statusLine) Render(elapsed time.Duration, size int64, reading meterReading, silenceWarning time.Duration) {
+	line := fmt.Sprintf("● %s  %s  %s %4.0f dB",
+		formatElapsed(elapsed), formatSize(size), vuMeter(reading.Level), reading.Level)
+
+	if silenceWarning > 0 && reading.SilentFor >= silenceWarning {
+		line += fmt.Sprintf("  ⚠ no input for %ds, check your microphone", int(reading.SilentFor.Seconds()))
+	}
+
+	fmt.Fprintf(s.w, "\r%s\033[K", line)
+}
+
+// Clear erases the line so regular output can continue
+func (s *statusLine) Clear() {
+	fmt.Fprint(s.w, "\r\033[K")
+}
+
+func vuMeter(level float64) string {
+	filled := int((level - meterFloorDB) / -meterFloorDB * meterWidth)
+	filled = max(0, min(meterWidth, filled))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", meterWidth-filled) + "]"
+}
+
+func formatElapsed(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", d/time.Hour, (d%time.Hour)/time.Minute, (d%time.Minute)/time.Second)
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,82 @@
+package recorder
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVUMeter(t *testing.T) {
+	tests := []struct {
+		level    float64
+		expected string
+	}{
+		{level: minLevelDB, expected: "[--------------------]"},
+		{level: meterFloorDB, expected: "[--------------------]"},
+		{level: -30, expected: "[##########----------]"},
+		{level: -3, expected: "[###################-]"},
+		{level: 0, expected: "[####################]"},
+		{level: 6, expected: "[####################]"},
+	}
+
+	for _, tt := range tests {
+		if got := vuMeter(tt.level); got != tt.expected {
+			t.Errorf("Expected %s for %.0f dB, got %s", tt.expected, tt.level, got)
+		}
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{d: 0, expected: "00:00:00"},
+		{d: 999 * time.Millisecond, expected: "00:00:00"},
+		{d: time.Hour + 2*time.Minute + 3*time.Second + 900*time.Millisecond, expected: "01:02:03"},
+		{d: 25 * time.Hour, expected: "25:00:00"},
+	}
+
+	for _, tt := range tests {
+		if got := formatElapsed(tt.d); got != tt.expected {
+			t.Errorf("Expected %s for %s, got %s", tt.expected, tt.d, got)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		n        int64
+		expected string
+	}{
+		{n: 0, expected: "0 B"},
+		{n: 1023, expected: "1023 B"},
+		{n: 1024, expected: "1.0 KB"},
+		{n: 1536, expected: "1.5 KB"},
+		{n: 1 << 20, expected: "1.0 MB"},
+		{n: 1<<30 + 1<<29, expected: "1536.0 MB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatSize(tt.n); got != tt.expected {
+			t.Errorf("Expected %s for %d bytes, got %s", tt.expected, tt.n, got)
+		}
+	}
+}
+
+func TestStatusLineRender(t *testing.T) {
+	var b bytes.Buffer
+	s := newStatusLine(&b)
+
+	s.Render(61*time.Second, 2048, meterReading{Level: -30, SilentFor: 4 * time.Second}, 5*time.Second)
+	if got := b.String(); got != "\r● 00:01:01  2.0 KB  [##########----------]  -30 dB\033[K" {
+		t.Errorf("Unexpected status line %q", got)
+	}
+
+	b.Reset()
+	s.Render(61*time.Second, 2048, meterReading{Level: minLevelDB, SilentFor: 6 * time.Second}, 5*time.Second)
+	if !strings.Contains(b.String(), "⚠ no input for 6s") {
+		t.Errorf("Expected a silence warning, got %q", b.String())
+	}
+}
@@ -0,0 +1,162 @@
+package recorder
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultVADThreshold  = -40.0
+	defaultVADFrame      = 30 * time.Millisecond
+	defaultVADMinSilence = 500 * time.Millisecond
+	defaultVADPadding    = 300 * time.Millisecond
+)
+
+// VADOptions configures the energy-based voice activity detector
+type VADOptions struct {
+	Threshold  float64       // Frame level in dBFS above which a frame counts as speech (default -40)
+	Frame      time.Duration // Length of the analysis frames (default 30ms)
+	MinSilence time.Duration // Shorter pauses are merged into the surrounding speech (default 500ms)
+	Padding    time.Duration // Audio kept around detected speech (default 300ms)
+}
+
+func (opts VADOptions) withDefaults() VADOptions {
+	if opts.Threshold == 0 {
+		opts.Threshold = defaultVADThreshold
+	}
+	if opts.Frame == 0 {
+		opts.Frame = defaultVADFrame
+	}
+	if opts.MinSilence == 0 {
+		opts.MinSilence = defaultVADMinSilence
+	}
+	if opts.Padding == 0 {
+		opts.Padding = defaultVADPadding
+	}
+	return opts
+}
+
+// Region is a span of detected speech
+type Region struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// VAD detects speech in a live 16-bit PCM stream
+type VAD struct {
+	opts       VADOptions
+	frameBytes int
+	pending    []byte
+	heard      bool
+	silence    time.Duration
+}
+
+// NewVAD creates a detector for a stream with the given layout
+func NewVAD(opts VADOptions, sampleRate, channels int) *VAD {
+	opts = opts.withDefaults()
+	return &VAD{
+		opts:       opts,
+		frameBytes: frameBytes(opts.Frame, wavFormat{SampleRate: sampleRate, Channels: channels, BitsPerSample: bitsPerSample}),
+	}
+}
+
+// Feed analyses the next chunk of the stream
+func (v *VAD) Feed(pcm []byte) {
+	v.pending = append(v.pending, pcm...)
+	for len(v.pending) >= v.frameBytes {
+		if rmsDBFS(v.pending[:v.frameBytes]) > v.opts.Threshold {
+			v.heard = true
+			v.silence = 0
+		} else {
+			v.silence += v.opts.Frame
+		}
+		v.pending = v.pending[v.frameBytes:]
+	}
+}
+
+// HeardSpeech reports whether any speech has been detected so far
+func (v *VAD) HeardSpeech() bool {
+	return v.heard
+}
+
+// Silence returns how long the stream has been silent since the last speech
+func (v *VAD) Silence() time.Duration {
+	return v.silence
+}
+
+// DetectSpeech returns the padded regions of speech in 16-bit PCM data
+func DetectSpeech(pcm []byte, sampleRate, channels int, opts VADOptions) []Region {
+	opts = opts.withDefaults()
+	format := wavFormat{SampleRate: sampleRate, Channels: channels, BitsPerSample: bitsPerSample}
+	size := frameBytes(opts.Frame, format)
+	total := bytesToDuration(len(pcm), format)
+
+	var regions []Region
+	for off := 0; off < len(pcm); off += size {
+		end := min(off+size, len(pcm))
+		if rmsDBFS(pcm[off:end]) <= opts.Threshold {
+			continue
+		}
+
+		start, stop := bytesToDuration(off, format), bytesToDuration(end, format)
+		if n := len(regions); n > 0 && start-regions[n-1].End <= opts.MinSilence {
+			regions[n-1].End = stop
+		} else {
+			regions = append(regions, Region{Start: start, End: stop})
+		}
+	}
+
+	// Pad the regions, merging those that now overlap
+	var padded []Region
+	for _, r := range regions {
+		r.Start = max(0, r.Start-opts.Padding)
+		r.End = min(total, r.End+opts.Padding)
+		if n := len(padded); n > 0 && r.Start <= padded[n-1].End {
+			padded[n-1].End = r.End
+		} else {
+			padded = append(padded, r)
+		}
+	}
+	return padded
+}
+
+// TrimSilence writes the WAV file at src without its leading and trailing
+// silence to dst and returns the duration removed. Nothing is written for files
+// without speech or silence to remove, and src is never changed.
+func TrimSilence(src, dst string, opts VADOptions) (time.Duration, error) {
+	format, pcm, err := readWAV(src)
+	if err != nil {
+		return 0, err
+	}
+
+	regions := DetectSpeech(pcm, format.SampleRate, format.Channels, opts)
+	if len(regions) == 0 {
+		return 0, nil
+	}
+
+	start := durationToBytes(regions[0].Start, format)
+	end := durationToBytes(regions[len(regions)-1].End, format)
+	if start == 0 && end >= len(pcm) {
+		return 0, nil
+	}
+
+	if err := writeWAV(dst, format, pcm[start:min(end, len(pcm))]); err != nil {
+		return 0, fmt.Errorf("writing trimmed audio: %w", err)
+	}
+	return bytesToDuration(len(pcm)-(end-start), format), nil
+}
+
+func frameBytes(d time.Duration, format wavFormat) int {
+	return max(durationToBytes(d, format), format.Channels*format.BitsPerSample/8)
+}
+
+// durationToBytes converts a duration to a byte offset aligned to a sample frame
+func durationToBytes(d time.Duration, format wavFormat) int {
+	frameSize := format.Channels * format.BitsPerSample / 8
+	frames := int(d.Seconds() * float64(format.SampleRate))
+	return frames * frameSize
+}
+
+func bytesToDuration(n int, format wavFormat) time.Duration {
+	return time.Duration(float64(n) / float64(format.bytesPerSecond()) * float64(time.Second))
+}
@@ -0,0 +1,123 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testPart struct {
+	tone bool
+	d    time.Duration
+}
+
+func part(tone bool, d time.Duration) testPart {
+	return testPart{tone: tone, d: d}
+}
+
+// testPCM builds 16 kHz mono PCM alternating silence and a 440 Hz tone
+func testPCM(parts ...testPart) []byte {
+	var pcm []byte
+	for _, p := range parts {
+		n := int(p.d.Seconds() * defaultSampleRate)
+		for i := 0; i < n; i++ {
+			var s int16
+			if p.tone {
+				s = int16(0.5 * math.MaxInt16 * math.Sin(2*math.Pi*440*float64(i)/defaultSampleRate))
+			}
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(s))
+		}
+	}
+	return pcm
+}
+
+func TestDetectSpeech(t *testing.T) {
+	pcm := testPCM(
+		part(false, 2*time.Second),
+		part(true, time.Second),
+		part(false, 200*time.Millisecond), // merged: shorter than MinSilence
+		part(true, time.Second),
+		part(false, 2*time.Second),
+		part(true, time.Second),
+		part(false, time.Second),
+	)
+
+	regions := DetectSpeech(pcm, defaultSampleRate, defaultChannels, VADOptions{})
+	if len(regions) != 2 {
+		t.Fatalf("Expected 2 regions, got %d: %v", len(regions), regions)
+	}
+
+	approx := func(got, want time.Duration) bool {
+		return got > want-50*time.Millisecond && got < want+50*time.Millisecond
+	}
+	if !approx(regions[0].Start, 1700*time.Millisecond) || !approx(regions[0].End, 4500*time.Millisecond) {
+		t.Errorf("Unexpected first region: %v", regions[0])
+	}
+	if !approx(regions[1].Start, 5900*time.Millisecond) || !approx(regions[1].End, 7500*time.Millisecond) {
+		t.Errorf("Unexpected second region: %v", regions[1])
+	}
+}
+
+func TestVADSilence(t *testing.T) {
+	vad := NewVAD(VADOptions{}, defaultSampleRate, defaultChannels)
+
+	vad.Feed(testPCM(part(false, time.Second)))
+	if vad.HeardSpeech() {
+		t.Fatal("Expected no speech in silence")
+	}
+
+	vad.Feed(testPCM(part(true, 500*time.Millisecond), part(false, 1500*time.Millisecond)))
+	if !vad.HeardSpeech() {
+		t.Fatal("Expected speech to be detected")
+	}
+	if got := vad.Silence(); got < 1400*time.Millisecond || got > 1600*time.Millisecond {
+		t.Errorf("Expected about 1.5s of trailing silence, got %s", got)
+	}
+}
+
+func TestTrimSilence(t *testing.T) {
+	dir := t.TempDir()
+	path, trimmedPath := filepath.Join(dir, "test.wav"), filepath.Join(dir, "trimmed.wav")
+	format := wavFormat{SampleRate: defaultSampleRate, Channels: defaultChannels, BitsPerSample: bitsPerSample}
+	pcm := testPCM(part(false, 3*time.Second), part(true, time.Second), part(false, 3*time.Second))
+	if err := writeWAV(path, format, pcm); err != nil {
+		t.Fatalf("writeWAV failed: %v", err)
+	}
+
+	trimmed, err := TrimSilence(path, trimmedPath, VADOptions{})
+	if err != nil {
+		t.Fatalf("TrimSilence failed: %v", err)
+	}
+	if trimmed < 5*time.Second {
+		t.Errorf("Expected over 5s trimmed, got %s", trimmed)
+	}
+
+	_, got, err := readWAV(trimmedPath)
+	if err != nil {
+		t.Fatalf("readWAV failed: %v", err)
+	}
+	if d := bytesToDuration(len(got), format); d < time.Second || d > 2*time.Second {
+		t.Errorf("Expected about 1.6s of audio left, got %s", d)
+	}
+
+	// The source is kept as it was
+	if _, original, err := readWAV(path); err != nil || len(original) != len(pcm) {
+		t.Errorf("Expected the source to be untouched, got %d bytes (%v)", len(original), err)
+	}
+
+	// Nothing is written without silence to remove
+	speech := filepath.Join(dir, "speech.wav")
+	if err := writeWAV(speech, format, testPCM(part(true, time.Second))); err != nil {
+		t.Fatalf("writeWAV failed: %v", err)
+	}
+	untrimmed := filepath.Join(dir, "untrimmed.wav")
+	if trimmed, err := TrimSilence(speech, untrimmed, VADOptions{}); err != nil || trimmed != 0 {
+		t.Errorf("Expected nothing to be trimmed, got %s (%v)", trimmed, err)
+	}
+	if _, err := os.Stat(untrimmed); !os.IsNotExist(err) {
+		t.Errorf("Expected no trimmed file, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	wavHeaderSize = 44
+	bitsPerSample = 16
+
+	// How often the header of a WAV being recorded is brought up to date, which
+	// bounds how much audio is lost if the process dies
+	headerPatchInterval = 2 * time.Second
+)
+
+// wavWriter streams 16-bit PCM into a WAV file. The RIFF sizes are patched
+// periodically while writing and finalized on Close, so the file stays
+// readable if the recording is interrupted.
+type wavWriter struct {
+	file       *os.File
+	sampleRate int
+	channels   int
+	dataSize   atomic.Int64
+	lastPatch  time.Time
+}
+
+func newWAVWriter(path string, sampleRate, channels int) (*wavWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wavWriter{
+		file:       file,
+		sampleRate: sampleRate,
+		channels:   channels,
+		lastPatch:  time.Now(),
+	}
+
+	if _, err := file.Write(w.header(0)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error writing WAV header: %w", err)
+	}
+
+	return w, nil
+}
+
+// Write appends raw little-endian PCM samples to the data chunk
+func (w *wavWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.dataSize.Add(int64(n))
+	if err != nil {
+		return n, err
+	}
+
+	if time.Since(w.lastPatch) >= headerPatchInterval {
+		if err := w.patchHeader(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// patchHeader writes the current sizes to the header and syncs the file to disk
+func (w *wavWriter) patchHeader() error {
+	w.lastPatch = time.Now()
+	if _, err := w.file.WriteAt(w.header(w.dataSize.Load()), 0); err != nil {
+		return fmt.Errorf("error updating WAV header: %w", err)
+	}
+	return w.file.Sync()
+}
+
+// Size returns the number of bytes written so far, including the header
+func (w *wavWriter) Size() int64 {
+	return wavHeaderSize + w.dataSize.Load()
+}
+
+// Close finalizes the header and closes the file
+func (w *wavWriter) Close() error {
+	if _, err := w.file.WriteAt(w.header(w.dataSize.Load()), 0); err != nil {
+		w.file.Close()
+		return fmt.Errorf("error finalizing WAV header: %w", err)
+	}
+	return w.file.Close()
+}
+
+func (w *wavWriter) header(dataSize int64) []byte {
+	blockAlign := w.channels * bitsPerSample / 8
+
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:8], uint32(36+dataSize))
+	copy(h[8:12], "WAVE")
+	copy(h[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:20], 16)
+	binary.LittleEndian.PutUint16(h[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(h[22:24], uint16(w.channels))
+	binary.LittleEndian.PutUint32(h[24:28], uint32(w.sampleRate))
+	binary.LittleEndian.PutUint32(h[28:32], uint32(w.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:36], bitsPerSample)
+	copy(h[36:40], "data")
+	binary.LittleEndian.PutUint32(h[40:44], uint32(dataSize))
+	return h
+}
+
+// wavFormat describes the PCM layout of a WAV file
+type wavFormat struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+}
+
+// bytesPerSecond returns the data rate of the PCM stream
+func (f wavFormat) bytesPerSecond() int {
+	return f.SampleRate * f.Channels * f.BitsPerSample / 8
+}
+
+// readWAV loads the format and raw PCM data of a 16-bit PCM WAV file
+func readWAV(path string) (wavFormat, []byte, error) {
+	var format wavFormat
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return format, nil, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return format, nil, fmt.Errorf("%s: not a WAV file", path)
+	}
+
+	var pcm []byte
+	for off := 12; off+8 <= len(data); {
+		id := string(data[off : off+4])
+		size := int(binary.LittleEndian.Uint32(data[off+4 : off+8]))
+		body := data[off+8 : min(off+8+size, len(data))]
+
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return format, nil, fmt.Errorf("%s: invalid fmt chunk", path)
+			}
+			if tag := binary.LittleEndian.Uint16(body[0:2]); tag != 1 {
+				return format, nil, fmt.Errorf("%s: unsupported WAV encoding %d", path, tag)
+			}
+			format.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			format.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			format.BitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+		case "data":
+			pcm = body
+		}
+
+		// Chunks are padded to an even size
+		off += 8 + size + size%2
+	}
+
+	switch {
+	case format.SampleRate == 0:
+		return format, nil, fmt.Errorf("%s: missing fmt chunk", path)
+	case pcm == nil:
+		return format, nil, fmt.Errorf("%s: missing data chunk", path)
+	case format.BitsPerSample != bitsPerSample:
+		return format, nil, fmt.Errorf("%s: unsupported bit depth %d", path, format.BitsPerSample)
+	}
+	return format, pcm, nil
+}
+
+// writeWAV atomically replaces path with a WAV file holding pcm
+func writeWAV(path string, format wavFormat, pcm []byte) error {
+	tmp := path + ".tmp"
+
+	w, err := newWAVWriter(tmp, format.SampleRate, format.Channels)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(pcm); err != nil {
+		w.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := w.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RepairResult describes the changes made by RepairWAV
+type RepairResult struct {
+	DeclaredSize int64         // Data size the header claimed
+	DataSize     int64         // Data size actually present in the file
+	Duration     time.Duration // Playable duration after the repair
+	Repaired     bool          // Whether the file was modified
+}
+
+// RepairWAV fixes the RIFF and data chunk sizes of a WAV file whose recording
+// was interrupted before the header was finalized. Trailing bytes that do not
+// form a complete sample frame are dropped.
+func RepairWAV(path string) (*RepairResult, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fileSize := info.Size()
+
+	// Only the chunk headers are needed; data is never read
+	header := make([]byte, min(fileSize, 4096))
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if len(header) < 12 || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%s: not a WAV file", path)
+	}
+
+	var format wavFormat
+	tag := uint16(0)
+	dataOffset := int64(-1)
+	for off := 12; off+8 <= len(header); {
+		id := string(header[off : off+4])
+		size := int(binary.LittleEndian.Uint32(header[off+4 : off+8]))
+
+		if id == "fmt " && off+8+16 <= len(header) {
+			body := header[off+8:]
+			tag = binary.LittleEndian.Uint16(body[0:2])
+			format.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			format.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			format.BitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+		}
+		if id == "data" {
+			dataOffset = int64(off + 8)
+			break
+		}
+		off += 8 + size + size%2
+	}
+
+	switch {
+	case format.SampleRate == 0 || format.Channels == 0 || format.BitsPerSample == 0:
+		return nil, fmt.Errorf("%s: missing or truncated fmt chunk", path)
+	case tag != 1:
+		return nil, fmt.Errorf("%s: unsupported WAV encoding %d", path, tag)
+	case dataOffset < 0:
+		return nil, fmt.Errorf("%s: missing data chunk", path)
+	}
+
+	frameSize := int64(format.Channels * format.BitsPerSample / 8)
+	if frameSize <= 0 {
+		return nil, fmt.Errorf("%s: unsupported sample format: %d channels of %d bits", path, format.Channels, format.BitsPerSample)
+	}
+	result := &RepairResult{
+		DeclaredSize: int64(binary.LittleEndian.Uint32(header[dataOffset-4 : dataOffset])),
+		DataSize:     (fileSize - dataOffset) / frameSize * frameSize,
+	}
+	result.Duration = bytesToDuration(int(result.DataSize), format)
+
+	// A consistent header may be followed by other chunks after the data
+	riffSize := int64(binary.LittleEndian.Uint32(header[4:8]))
+	if riffSize+8 == fileSize && result.DeclaredSize > 0 && dataOffset+result.DeclaredSize <= fileSize {
+		result.DataSize = result.DeclaredSize
+		result.Duration = bytesToDuration(int(result.DataSize), format)
+		return result, nil
+	}
+
+	if err := file.Truncate(dataOffset + result.DataSize); err != nil {
+		return nil, fmt.Errorf("truncating partial frame: %w", err)
+	}
+
+	sizes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sizes, uint32(dataOffset-8+result.DataSize))
+	if _, err := file.WriteAt(sizes, 4); err != nil {
+		return nil, fmt.Errorf("writing RIFF size: %w", err)
+	}
+	binary.LittleEndian.PutUint32(sizes, uint32(result.DataSize))
+	if _, err := file.WriteAt(sizes, dataOffset-4); err != nil {
+		return nil, fmt.Errorf("writing data size: %w", err)
+	}
+
+	result.Repaired = true
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRepairWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "interrupted.wav")
+
+	// Simulate a recording killed before the header was finalized: sizes are
+	// still zero and the last sample frame is incomplete
+	w, err := newWAVWriter(path, defaultSampleRate, defaultChannels)
+	if err != nil {
+		t.Fatalf("newWAVWriter failed: %v", err)
+	}
+	pcm := testPCM(part(true, time.Second))
+	if _, err := w.file.Write(append(pcm, 0x01)); err != nil {
+		t.Fatalf("Error writing PCM: %v", err)
+	}
+	w.file.Close()
+
+	result, err := RepairWAV(path)
+	if err != nil {
+		t.Fatalf("RepairWAV failed: %v", err)
+	}
+	if !result.Repaired {
+		t.Error("Expected the file to be repaired")
+	}
+	if result.DeclaredSize != 0 || result.DataSize != int64(len(pcm)) {
+		t.Errorf("Unexpected sizes: declared %d, actual %d", result.DeclaredSize, result.DataSize)
+	}
+	if result.Duration != time.Second {
+		t.Errorf("Expected 1s duration, got %s", result.Duration)
+	}
+
+	_, got, err := readWAV(path)
+	if err != nil {
+		t.Fatalf("readWAV failed: %v", err)
+	}
+	if len(got) != len(pcm) {
+		t.Errorf("Expected %d bytes of PCM, got %d", len(pcm), len(got))
+	}
+
+	// A second pass finds nothing to fix
+	result, err = RepairWAV(path)
+	if err != nil {
+		t.Fatalf("RepairWAV failed: %v", err)
+	}
+	if result.Repaired {
+		t.Error("Expected a valid file to be left untouched")
+	}
+}
+
+func TestRepairWAVInvalidFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		tag           uint16
+		bitsPerSample uint16
+		err           string
+	}{
+		{name: "Float", tag: 3, bitsPerSample: 32, err: "unsupported WAV encoding 3"},
+		{name: "EmptyFrame", tag: 1, bitsPerSample: 4, err: "unsupported sample format: 1 channels of 4 bits"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "invalid.wav")
+			w, err := newWAVWriter(path, defaultSampleRate, 1)
+			if err != nil {
+				t.Fatalf("newWAVWriter failed: %v", err)
+			}
+			w.file.Write(make([]byte, 100))
+			header := w.header(0)
+			binary.LittleEndian.PutUint16(header[20:22], tc.tag)
+			binary.LittleEndian.PutUint16(header[34:36], tc.bitsPerSample)
+			w.file.WriteAt(header, 0)
+			w.file.Close()
+
+			_, err = RepairWAV(path)
+			if err == nil || err.Error() != path+": "+tc.err {
+				t.Errorf("Expected %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestWAVWriterPatchesHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "live.wav")
+
+	w, err := newWAVWriter(path, defaultSampleRate, defaultChannels)
+	if err != nil {
+		t.Fatalf("newWAVWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	pcm := testPCM(part(true, 100*time.Millisecond))
+	w.lastPatch = time.Now().Add(-headerPatchInterval)
+	if _, err := w.Write(pcm); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	header := make([]byte, wavHeaderSize)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Error opening file: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Read(header); err != nil {
+		t.Fatalf("Error reading header: %v", err)
+	}
+
+	if got := binary.LittleEndian.Uint32(header[40:44]); got != uint32(len(pcm)) {
+		t.Errorf("Expected data size %d in header before Close, got %d", len(pcm), got)
+	}
+}
@@ -0,0 +1,60 @@
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/usage"
+)
+
+// MetadataFile is the name of the metadata file in each recording folder
+const MetadataFile = "metadata.json"
+
+// TimestampLayout is the layout of recording timestamps and folder name prefixes
+const TimestampLayout = "20060102_150405"
+
+// Metadata describes a recording, so the library can be filtered and searched
+type Metadata struct {
+	Timestamp string           `json:"timestamp"`
+	Date      time.Time        `json:"date"`
+	Headline  string           `json:"headline,omitempty"`
+	Slug      string           `json:"slug,omitempty"`      // Names the folder after the timestamp
+	Headlines editor.Headlines `json:"headlines,omitempty"` // Candidates in all styles, best first
+	AudioFile string           `json:"audio_file,omitempty"`
+
+	Language     string   `json:"language,omitempty"`
+	Translations []string `json:"translations,omitempty"`
+
+	Tags     []string         `json:"tags,omitempty"`
+	Entities *editor.Entities `json:"entities,omitempty"`
+	Category string           `json:"category,omitempty"`
+
+	Usage *usage.Summary `json:"usage,omitempty"`
+}
+
+// LoadMetadata reads the metadata of the recording in dir
+func LoadMetadata(dir string) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MetadataFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Join(dir, MetadataFile), err)
+	}
+	return &m, nil
+}
+
+// Save writes the metadata into the recording folder dir
+func (m *Metadata) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding metadata: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, MetadataFile), append(data, '\n'), 0644)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/r4h4/article-helper/editor"
+	"github.com/r4h4/article-helper/glossary"
+)
+
+// historyFile holds all revisions of a reviewed recording
+const historyFile = "history.json"
+
+// Reviewer asks the user during a review, e.g. in the terminal
+type Reviewer interface {
+	// Choose returns the index of the item the user picked
+	Choose(label string, items []string) (int, error)
+	// Ask returns the text the user entered
+	Ask(label string) (string, error)
+	// Edit returns text as changed by the user
+	Edit(text string) (string, error)
+}
+
+// Revision is a version of a reviewed field, in the order they were made
+type Revision struct {
+	Time         time.Time `json:"time"`
+	Field        Field     `json:"field"`
+	Source       string    `json:"source"` // "model", "editor", "regenerated" or "candidate"
+	Instructions string    `json:"instructions,omitempty"`
+	Text         string    `json:"text"`
+}
+
+// ReviewStep shows the results to the user, who accepts or revises them
+type ReviewStep struct {
+	Editor   Editor
+	Reviewer Reviewer
+	Glossary *glossary.Glossary
+	// Candidates is the number of headlines per style when regenerating
+	Candidates int
+
+	history []Revision
+}
+
+func (s *ReviewStep) Name() string { return "review" }
+func (s *ReviewStep) Requires() []Field {
+	return []Field{FieldCleaned, FieldSummary, FieldHeadline, FieldHeadlines}
+}
+
+// Produces includes the slug and candidates, as choosing and regenerating headlines changes them
+func (s *ReviewStep) Produces() []Field {
+	return []Field{FieldCleaned, FieldSummary, FieldHeadline, FieldSlug, FieldHeadlines}
+}
+
+const (
+	reviewAccept     = "Accept"
+	reviewTranscript = "Edit transcript"
+	reviewSummary    = "Edit summary"
+	reviewRegenerate = "Regenerate with instructions"
+	reviewHeadline   = "Choose headline"
+)
+
+func (s *ReviewStep) Execute(ctx context.Context, state *State) error {
+	s.history = nil
+	s.revise(state, FieldCleaned, "model", "")
+	s.revise(state, FieldSummary, "model", "")
+	s.revise(state, FieldHeadline, "model", "")
+
+	for {
+		if err := s.saveHistory(state); err != nil {
+			return err
+		}
+
+		fmt.Printf("\n%s\n\nCleaned Transcription:\n%s\n\nSummary:\n%s\n\n", state.Headline, state.CleanedTranscription, state.Summary)
+		actions := []string{reviewAccept, reviewTranscript, reviewSummary, reviewRegenerate}
+		titles := state.Headlines.Of(editor.HeadlineTitle)
+		if len(titles) > 1 {
+			actions = append(actions, reviewHeadline)
+		}
+		i, err := s.Reviewer.Choose("Review", actions)
+		if err != nil {
+			return fmt.Errorf("reviewing: %w", err)
+		}
+
+		switch actions[i] {
+		case reviewAccept:
+			return nil
+		case reviewTranscript:
+			// A failed editor returns no text, which must not replace the field
+			edited, err := s.Reviewer.Edit(state.CleanedTranscription)
+			if err != nil {
+				return fmt.Errorf("editing transcript: %w", err)
+			}
+			state.CleanedTranscription = edited
+			s.revise(state, FieldCleaned, "editor", "")
+		case reviewSummary:
+			edited, err := s.Reviewer.Edit(state.Summary)
+			if err != nil {
+				return fmt.Errorf("editing summary: %w", err)
+			}
+			state.Summary = edited
+			s.revise(state, FieldSummary, "editor", "")
+		case reviewRegenerate:
+			if err := s.regenerate(ctx, state); err != nil {
+				return err
+			}
+		case reviewHeadline:
+			i, err := s.Reviewer.Choose("Headline", titles)
+			if err != nil {
+				return fmt.Errorf("choosing headline: %w", err)
+			}
+			state.Headline = titles[i]
+			state.Slug = editor.Slugify(titles[i])
+			s.revise(state, FieldHeadline, "candidate", "")
+		}
+	}
+}
+
+// regenerate edits the transcript again with instructions from the user, and
+// creates new headlines for the new summary
+func (s *ReviewStep) regenerate(ctx context.Context, state *State) error {
+	instructions, err := s.Reviewer.Ask("Instructions, e.g. shorter or more formal")
+	if err != nil {
+		return fmt.Errorf("asking for instructions: %w", err)
+	}
+
+	opts := editor.EditOptions{Language: state.Language, Instructions: instructions}
+	if s.Glossary != nil {
+		opts.Glossary = s.Glossary.String()
+	}
+	edited, err := s.Editor.EditAndSummarize(ctx, state.EditorInput(), opts)
+	if err != nil {
+		return fmt.Errorf("regenerating: %w", err)
+	}
+	state.CleanedTranscription = edited.CleanedTranscription
+	state.Summary = edited.Summary
+	s.revise(state, FieldCleaned, "regenerated", instructions)
+	s.revise(state, FieldSummary, "regenerated", instructions)
+
+	if err := (&HeadlineStep{Editor: s.Editor, Candidates: s.Candidates}).Execute(ctx, state); err != nil {
+		return err
+	}
+	s.revise(state, FieldHeadline, "regenerated", instructions)
+	return nil
+}
+
+// revise records the current value of a field
+func (s *ReviewStep) revise(state *State, field Field, source, instructions string) {
+	text := map[Field]string{
+		FieldCleaned:  state.CleanedTranscription,
+		FieldSummary:  state.Summary,
+		FieldHeadline: state.Headline,
+	}[field]
+	s.history = append(s.history, Revision{Time: time.Now(), Field: field, Source: source, Instructions: instructions, Text: text})
+}
+
+// saveHistory writes the revisions so far, so they are kept if the review is aborted
+func (s *ReviewStep) saveHistory(state *State) error {
+	data, err := json.MarshalIndent(s.history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding history: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(state.OutFolder, historyFile), data, 0644); err != nil {
+		return fmt.Errorf("saving history: %w", err)
+	}
+	return nil
+}
+
+// terminalReviewer reviews with prompts in the terminal and the user's editor
+type terminalReviewer struct{}
+
+func (terminalReviewer) Choose(label string, items []string) (int, error) {
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+		Size:  len(items),
+	}
+	i, _, err := prompt.Run()
+	if errors.Is(err, promptui.ErrInterrupt) {
+		return 0, context.Canceled
+	}
+	return i, err
+}
+
+func (terminalReviewer) Ask(label string) (string, error) {
+	prompt := promptui.Prompt{Label: label}
+	text, err := prompt.Run()
+	if errors.Is(err, promptui.ErrInterrupt) {
+		return "", context.Canceled
+	}
+	return text, err
+}
+
+// Edit opens the text in $VISUAL or $EDITOR, falling back to vi
+func (terminalReviewer) Edit(text string) (string, error) {
+	f, err := os.CreateTemp("", "article-helper-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	command := os.Getenv("VISUAL")
+	if command == "" {
+		command = os.Getenv("EDITOR")
+	}
+	if command == "" {
+		command = "vi"
+	}
+	// The editor may take arguments, e.g. "code --wait"
+	args := append(strings.Fields(command), f.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running %s: %w", args[0], err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(edited), "\n"), nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/r4h4/article-helper/editor"
+)
+
+// scriptedReviewer answers with the given choices and texts in order, and
+// fails to edit with editErr if it is set
+type scriptedReviewer struct {
+	choices []string
+	texts   []string
+	editErr error
+}
+
+func (r *scriptedReviewer) Choose(label string, items []string) (int, error) {
+	if len(r.choices) == 0 {
+		return 0, errors.New("unexpected prompt " + label)
+	}
+	choice := r.choices[0]
+	r.choices = r.choices[1:]
+	for i, item := range items {
+		if item == choice {
+			return i, nil
+		}
+	}
+	return 0, errors.New(choice + " not offered")
+}
+
+func (r *scriptedReviewer) Ask(label string) (string, error) { return r.next() }
+
+func (r *scriptedReviewer) Edit(text string) (string, error) {
+	if r.editErr != nil {
+		return "", r.editErr
+	}
+	return r.next()
+}
+
+func (r *scriptedReviewer) next() (string, error) {
+	if len(r.texts) == 0 {
+		return "", errors.New("unexpected text prompt")
+	}
+	text := r.texts[0]
+	r.texts = r.texts[1:]
+	return text, nil
+}
+
+// instructedEditor follows the instructions literally
+type instructedEditor struct{ fakeEditor }
+
+func (instructedEditor) EditAndSummarize(ctx context.Context, transcript string, opts editor.EditOptions) (*editor.EditorResponse, error) {
+	return &editor.EditorResponse{CleanedTranscription: "cleaned", Summary: opts.Instructions + " summary"}, nil
+}
+
+func (instructedEditor) CreateHeadline(ctx context.Context, summary string, n int) (*editor.HeadlineResponse, error) {
+	return &editor.HeadlineResponse{
+		Headlines: editor.Headlines{
+			{Style: editor.HeadlineTitle, Text: "New Headline"},
+			{Style: editor.HeadlineSEO, Text: "A New Headline for Search Engines"},
+			{Style: editor.HeadlineTitle, Text: "Other Headline"},
+		},
+		Slug: "new-headline",
+	}, nil
+}
+
+func TestReviewStep(t *testing.T) {
+	state := &State{
+		OutFolder:            t.TempDir(),
+		CleanedTranscription: "cleaned",
+		Summary:              "summary",
+		Headline:             "Headline",
+		Slug:                 "headline",
+		Headlines:            editor.Headlines{{Style: editor.HeadlineTitle, Text: "Headline"}},
+	}
+	reviewer := &scriptedReviewer{
+		choices: []string{reviewSummary, reviewRegenerate, reviewHeadline, "Other Headline", reviewAccept},
+		texts:   []string{"edited summary", "shorter"},
+	}
+	step := &ReviewStep{Editor: instructedEditor{}, Reviewer: reviewer}
+
+	if err := step.Execute(context.Background(), state); err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if state.Summary != "shorter summary" || state.Headline != "Other Headline" {
+		t.Errorf("Expected the regenerated summary and chosen headline, got %q and %q", state.Summary, state.Headline)
+	}
+	if state.Slug != "other-headline" {
+		t.Errorf("Expected the slug of the chosen headline, got %q", state.Slug)
+	}
+
+	data, err := os.ReadFile(filepath.Join(state.OutFolder, historyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var history []Revision
+	if err := json.Unmarshal(data, &history); err != nil {
+		t.Fatal(err)
+	}
+	var summaries []string
+	for _, r := range history {
+		if r.Field == FieldSummary {
+			summaries = append(summaries, r.Source+": "+r.Text)
+		}
+	}
+	expected := []string{"model: summary", "editor: edited summary", "regenerated: shorter summary"}
+	if len(history) != 8 || len(summaries) != 3 || summaries[0] != expected[0] || summaries[1] != expected[1] || summaries[2] != expected[2] {
+		t.Errorf("Expected summary revisions %v in %d revisions, got %v in %d", expected, 8, summaries, len(history))
+	}
+	if last := history[len(history)-1]; last.Field != FieldHeadline || last.Source != "candidate" {
+		t.Errorf("Expected the chosen headline last, got %+v", last)
+	}
+}
+
+func TestReviewStepEditFailed(t *testing.T) {
+	editorErr := errors.New("vi exited with status 1")
+	for _, action := range []string{reviewTranscript, reviewSummary} {
+		state := &State{
+			OutFolder:            t.TempDir(),
+			CleanedTranscription: "cleaned",
+			Summary:              "summary",
+			Headline:             "Headline",
+		}
+		reviewer := &scriptedReviewer{choices: []string{action}, editErr: editorErr}
+		step := &ReviewStep{Editor: instructedEditor{}, Reviewer: reviewer}
+
+		if err := step.Execute(context.Background(), state); !errors.Is(err, editorErr) {
+			t.Errorf("Expected %s to fail with the editor, got %v", action, err)
+		}
+		if state.CleanedTranscription != "cleaned" || state.Summary != "summary" {
+			t.Errorf("Expected %s to keep the text, got %q and %q", action, state.CleanedTranscription, state.Summary)
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r4h4/article-helper/usage"
+)
+
+const (
+	// jobsDir holds the job files and uploads, hidden among the recording folders
+	jobsDir    = ".jobs"
+	uploadsDir = "uploads"
+
+	maxUploadSize = 200 << 20
+	maxQueuedJobs = 64
+)
+
+// uploadExtensions are the audio formats accepted for upload
+var uploadExtensions = []string{".flac", ".m4a", ".mp3", ".mp4", ".mpeg", ".mpga", ".ogg", ".wav", ".webm"}
+
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// JobOptions override the server's pipeline options for a single job
+type JobOptions struct {
+	Language    string   `json:"language,omitempty"`
+	Diarize     bool     `json:"diarize,omitempty"`
+	Actions     bool     `json:"actions,omitempty"`
+	Tags        bool     `json:"tags,omitempty"`
+	TranslateTo []string `json:"translate_to,omitempty"`
+}
+
+// Job is a pipeline run on an uploaded file
+type Job struct {
+	ID      string     `json:"id"`
+	Status  JobStatus  `json:"status"`
+	Step    string     `json:"step,omitempty"` // Step being executed
+	Error   string     `json:"error,omitempty"`
+	Upload  string     `json:"upload"`
+	Options JobOptions `json:"options"`
+	Created time.Time  `json:"created"`
+	Updated time.Time  `json:"updated"`
+
+	Folder               string `json:"folder,omitempty"`
+	Headline             string `json:"headline,omitempty"`
+	Language             string `json:"language,omitempty"`
+	Transcription        string `json:"transcription,omitempty"`
+	CleanedTranscription string `json:"cleaned_transcription,omitempty"`
+	Summary              string `json:"summary,omitempty"`
+
+	Usage *usage.Summary `json:"usage,omitempty"`
+}
+
+// jobStore persists jobs as one JSON file each, so they survive restarts
+type jobStore struct {
+	dir  string
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func openJobStore(dir string) (*jobStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, uploadsDir), 0755); err != nil {
+		return nil, fmt.Errorf("creating jobs folder: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &jobStore{dir: dir, jobs: make(map[string]*Job)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading job: %w", err)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("parsing job %s: %w", path, err)
+		}
+		s.jobs[job.ID] = &job
+	}
+	return s, nil
+}
+
+// get returns a copy of the job, so it can be read while a worker updates it
+func (s *jobStore) get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// list returns copies of all jobs, newest first
+func (s *jobStore) list() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.After(jobs[j].Created) })
+	return jobs
+}
+
+// update applies fn to the job and saves it
+func (s *jobStore) update(id string, fn func(*Job)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		job = &Job{ID: id}
+		s.jobs[id] = job
+	}
+	fn(job)
+	job.Updated = time.Now()
+
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding job: %w", err)
+	}
+	path := filepath.Join(s.dir, id+".json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("writing job: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// server runs pipeline jobs on uploaded audio in a bounded worker pool
+type server struct {
+	opts      pipelineOptions
+	jobs      *jobStore
+	queue     chan string
+	token     string
+	maxUpload int64 // Bytes
+}
+
+// runServe starts the HTTP API:
+//
+//	POST /uploads            upload an audio file as multipart field "file"
+//	POST /jobs               start a job, e.g. {"upload": "<id>", "actions": true}
+//	GET  /jobs               list jobs
+//	GET  /jobs/{id}          poll the status of a job
+//	GET  /jobs/{id}/result   fetch the headline, transcript and summary of a finished job
+func runServe(ctx context.Context, opts pipelineOptions, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	workers := fs.Int("workers", 2, "Number of jobs to process in parallel")
+	token := fs.String("token", os.Getenv("ARTICLE_HELPER_TOKEN"), "Bearer token required by all requests (default: $ARTICLE_HELPER_TOKEN)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *workers < 1 {
+		return fmt.Errorf("-workers must be at least 1")
+	}
+
+	jobs, err := openJobStore(filepath.Join(recordingsDir, jobsDir))
+	if err != nil {
+		return err
+	}
+	s := &server{
+		opts:      opts,
+		jobs:      jobs,
+		queue:     make(chan string, maxQueuedJobs),
+		token:     *token,
+		maxUpload: maxUploadSize,
+	}
+
+	pending, err := s.resumeJobs()
+	if err != nil {
+		return err
+	}
+	go func() {
+		// This blocks when there are more jobs than the queue holds
+		for _, id := range pending {
+			select {
+			case s.queue <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Listening", "url", "http://"+*addr, "workers", *workers)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	wg.Wait()
+	return nil
+}
+
+// resumeJobs returns the IDs of the jobs queued by the previous run, oldest
+// first. Running jobs were cut off and may have left a partial recording
+// folder behind, so they fail instead of being retried.
+func (s *server) resumeJobs() ([]string, error) {
+	var pending []string
+	for _, job := range s.jobs.list() {
+		switch job.Status {
+		case JobQueued:
+			pending = append(pending, job.ID)
+		case JobRunning:
+			if err := s.jobs.update(job.ID, func(j *Job) {
+				j.Status, j.Error = JobFailed, "interrupted by server shutdown"
+			}); err != nil {
+				return nil, err
+			}
+			s.removeUpload(job.Upload)
+		}
+	}
+	slices.Reverse(pending)
+	return pending, nil
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /uploads", s.handleUpload)
+	mux.HandleFunc("POST /jobs", s.handleCreateJob)
+	mux.HandleFunc("GET /jobs", s.handleListJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("GET /jobs/{id}/result", s.handleJobResult)
+	return s.authenticate(mux)
+}
+
+func (s *server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "invalid or missing token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("reading multipart field \"file\": %v", err))
+		return
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if !slices.Contains(uploadExtensions, ext) {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported file extension %q, expected one of %s", ext, strings.Join(uploadExtensions, ", ")))
+		return
+	}
+
+	id := newID()
+	name := id + ext
+	dst, err := os.Create(filepath.Join(s.jobs.dir, uploadsDir, name))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storing upload failed")
+		slog.Error("Storing upload failed", "err", err)
+		return
+	}
+	if _, err := io.Copy(dst, file); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("reading upload: %v", err))
+		return
+	}
+	if err := dst.Close(); err != nil {
+		writeError(w, http.StatusInternalServerError, "storing upload failed")
+		slog.Error("Storing upload failed", "err", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]string{"upload": name})
+}
+
+func (s *server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Upload string `json:"upload"`
+		JobOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("parsing request: %v", err))
+		return
+	}
+
+	// Uploads are referenced by name; reject anything that is not a plain file name
+	if req.Upload == "" || req.Upload != filepath.Base(req.Upload) {
+		writeError(w, http.StatusBadRequest, "missing or invalid upload")
+		return
+	}
+	upload := filepath.Join(s.jobs.dir, uploadsDir, req.Upload)
+	if _, err := os.Stat(upload); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("upload %s not found", req.Upload))
+		return
+	}
+
+	id := newID()
+	if err := s.jobs.update(id, func(j *Job) {
+		j.Status = JobQueued
+		j.Upload = req.Upload
+		j.Options = req.JobOptions
+		j.Created = time.Now()
+	}); err != nil {
+		writeError(w, http.StatusInternalServerError, "saving job failed")
+		slog.Error("Saving job failed", "err", err)
+		return
+	}
+
+	select {
+	case s.queue <- id:
+	default:
+		s.jobs.update(id, func(j *Job) { j.Status, j.Error = JobFailed, "queue is full" })
+		s.removeUpload(req.Upload)
+		writeError(w, http.StatusServiceUnavailable, "too many queued jobs, try again later")
+		return
+	}
+
+	job, _ := s.jobs.get(id)
+	w.Header().Set("Location", "/jobs/"+id)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobs.list())
+}
+
+func (s *server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *server) handleJobResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if job.Status != JobDone {
+		writeError(w, http.StatusConflict, fmt.Sprintf("job is %s", job.Status))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"folder":                job.Folder,
+		"headline":              job.Headline,
+		"language":              job.Language,
+		"transcription":         job.Transcription,
+		"cleaned_transcription": job.CleanedTranscription,
+		"summary":               job.Summary,
+	})
+}
+
+// work runs queued jobs until ctx is cancelled
+func (s *server) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			if err := s.runJob(ctx, id); err != nil {
+				slog.Error("Job failed", "job", id, "err", err)
+			}
+		}
+	}
+}
+
+func (s *server) runJob(ctx context.Context, id string) error {
+	job, ok := s.jobs.get(id)
+	if !ok {
+		return fmt.Errorf("job not found")
+	}
+
+	opts := s.opts
+	opts.Input = filepath.Join(s.jobs.dir, uploadsDir, job.Upload)
+	// Results are fetched from the API, not printed to the server's log
+	opts.Quiet = true
+	opts.Diarize = opts.Diarize || job.Options.Diarize
+	opts.Actions = opts.Actions || job.Options.Actions
+	opts.Tags = opts.Tags || job.Options.Tags
+	if job.Options.Language != "" {
+		opts.Language = job.Options.Language
+	}
+	if len(job.Options.TranslateTo) > 0 {
+		opts.TranslateTo = job.Options.TranslateTo
+	}
+
+	state, pipeline, err := newRun(opts)
+	if err == nil {
+		err = s.jobs.update(id, func(j *Job) { j.Status = JobRunning })
+	}
+	if err == nil {
+		err = runPipeline(ctx, pipeline, state, func(step Step) {
+			s.jobs.update(id, func(j *Job) { j.Step = step.Name() })
+		})
+	}
+
+	if updateErr := s.jobs.update(id, func(j *Job) {
+		j.Step = ""
+		if state != nil {
+			j.Folder = state.OutFolder
+			j.Headline = state.Headline
+			j.Language = state.Language
+			j.Transcription = state.Transcription
+			j.CleanedTranscription = state.CleanedTranscription
+			j.Summary = state.Summary
+			j.Usage = state.Usage.Summary()
+		}
+		if err != nil {
+			j.Status, j.Error = JobFailed, err.Error()
+		} else {
+			j.Status = JobDone
+		}
+	}); updateErr != nil {
+		return updateErr
+	}
+
+	// Jobs are not retried, and the recording folder holds a copy
+	s.removeUpload(job.Upload)
+	return err
+}
+
+// removeUpload deletes an upload once its job has finished, failed or been cancelled
+func (s *server) removeUpload(name string) {
+	if err := os.Remove(filepath.Join(s.jobs.dir, uploadsDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("Removing upload failed", "upload", name, "err", err)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
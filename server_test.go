@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r4h4/article-helper/internal/fakeopenai"
+)
+
+func newTestServer(t *testing.T, opts pipelineOptions) *server {
+	t.Helper()
+
+	jobs, err := openJobStore(filepath.Join(t.TempDir(), jobsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &server{opts: opts, jobs: jobs, queue: make(chan string, maxQueuedJobs), maxUpload: maxUploadSize}
+}
+
+// serve sends a request to the server's handler, with body encoded as JSON unless it is a reader
+func serve(s *server, method, path string, header http.Header, body any) *httptest.ResponseRecorder {
+	reader, ok := body.(*bytes.Buffer)
+	if !ok {
+		reader = new(bytes.Buffer)
+		if body != nil {
+			json.NewEncoder(reader).Encode(body)
+		}
+	}
+	req := httptest.NewRequest(method, path, reader)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+	return rec
+}
+
+// upload posts data as the multipart field "file" and returns the response
+func upload(t *testing.T, s *server, name string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+	return serve(s, http.MethodPost, "/uploads", http.Header{"Content-Type": {mw.FormDataContentType()}}, &b)
+}
+
+// uploadName uploads data and returns the name to create a job with
+func uploadName(t *testing.T, s *server, name string, data []byte) string {
+	t.Helper()
+
+	rec := upload(t, s, name, data)
+	var resp struct{ Upload string }
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); rec.Code != http.StatusCreated || err != nil {
+		t.Fatalf("Upload failed with status %d: %s", rec.Code, rec.Body)
+	}
+	return resp.Upload
+}
+
+func uploadExists(s *server, name string) bool {
+	_, err := os.Stat(filepath.Join(s.jobs.dir, uploadsDir, name))
+	return err == nil
+}
+
+func TestServerAuth(t *testing.T) {
+	s := newTestServer(t, pipelineOptions{})
+	s.token = "secret"
+
+	for _, tc := range []struct {
+		auth   string
+		status int
+	}{
+		{auth: "", status: http.StatusUnauthorized},
+		{auth: "Bearer wrong", status: http.StatusUnauthorized},
+		{auth: "Bearer secret", status: http.StatusOK},
+	} {
+		rec := serve(s, http.MethodGet, "/jobs", http.Header{"Authorization": {tc.auth}}, nil)
+		if rec.Code != tc.status {
+			t.Errorf("Expected status %d with %q, got %d", tc.status, tc.auth, rec.Code)
+		}
+	}
+}
+
+func TestServerUpload(t *testing.T) {
+	s := newTestServer(t, pipelineOptions{})
+	s.maxUpload = 1024
+
+	name := uploadName(t, s, "memo.MP3", []byte("ID3"))
+	if !strings.HasSuffix(name, ".mp3") || !uploadExists(s, name) {
+		t.Errorf("Expected the upload to be stored, got %q", name)
+	}
+
+	if rec := upload(t, s, "memo.mp3", make([]byte, 2048)); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "too large") {
+		t.Errorf("Expected an upload over the limit to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := upload(t, s, "notes.txt", []byte("text")); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected an unsupported extension to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodPost, "/uploads", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a missing file to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Only the accepted upload is kept
+	if entries, _ := os.ReadDir(filepath.Join(s.jobs.dir, uploadsDir)); len(entries) != 1 {
+		t.Errorf("Expected 1 stored upload, got %v", entries)
+	}
+}
+
+func TestServerCreateJob(t *testing.T) {
+	s := newTestServer(t, pipelineOptions{})
+	s.queue = make(chan string, 1)
+
+	for _, tc := range []struct {
+		upload string
+		status int
+	}{
+		{upload: "", status: http.StatusBadRequest},
+		{upload: "../jobs.json", status: http.StatusBadRequest},
+		{upload: "missing.mp3", status: http.StatusNotFound},
+	} {
+		if rec := serve(s, http.MethodPost, "/jobs", nil, map[string]string{"upload": tc.upload}); rec.Code != tc.status {
+			t.Errorf("Expected status %d for upload %q, got %d: %s", tc.status, tc.upload, rec.Code, rec.Body)
+		}
+	}
+
+	name := uploadName(t, s, "memo.mp3", []byte("ID3"))
+	rec := serve(s, http.MethodPost, "/jobs", nil, map[string]any{"upload": name, "actions": true})
+	var job Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); rec.Code != http.StatusAccepted || err != nil {
+		t.Fatalf("Creating job failed with status %d: %s", rec.Code, rec.Body)
+	}
+	if job.Status != JobQueued || !job.Options.Actions || rec.Header().Get("Location") != "/jobs/"+job.ID {
+		t.Errorf("Unexpected job %+v", job)
+	}
+
+	// The job is persisted, and survives reopening the store
+	jobs, err := openJobStore(s.jobs.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved, ok := jobs.get(job.ID); !ok || saved.Upload != name || saved.Status != JobQueued {
+		t.Errorf("Expected the job to be saved, got %+v", saved)
+	}
+
+	// A full queue fails the job and removes its upload
+	name = uploadName(t, s, "other.mp3", []byte("ID3"))
+	if rec := serve(s, http.MethodPost, "/jobs", nil, map[string]string{"upload": name}); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the queue to be full, got %d: %s", rec.Code, rec.Body)
+	}
+	if uploadExists(s, name) {
+		t.Error("Expected the upload of the rejected job to be removed")
+	}
+	if jobs := s.jobs.list(); len(jobs) != 2 || jobs[0].Status != JobFailed || jobs[0].Error != "queue is full" {
+		t.Errorf("Expected the rejected job to fail, got %+v", jobs)
+	}
+}
+
+func TestServerResumeJobs(t *testing.T) {
+	s := newTestServer(t, pipelineOptions{})
+	start := time.Now()
+	for i, status := range []JobStatus{JobQueued, JobRunning, JobDone, JobQueued} {
+		name := uploadName(t, s, "memo.mp3", []byte("ID3"))
+		s.jobs.update([]string{"a", "b", "c", "d"}[i], func(j *Job) {
+			j.Status, j.Upload, j.Created = status, name, start.Add(time.Duration(i)*time.Second)
+		})
+	}
+
+	jobs, err := openJobStore(s.jobs.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.jobs = jobs
+	pending, err := s.resumeJobs()
+	if err != nil {
+		t.Fatalf("resumeJobs failed: %v", err)
+	}
+
+	// Queued jobs are resumed oldest first, running ones were cut off
+	if !reflect.DeepEqual(pending, []string{"a", "d"}) {
+		t.Errorf("Expected jobs a and d to be resumed, got %v", pending)
+	}
+	job, _ := s.jobs.get("b")
+	if job.Status != JobFailed || job.Error != "interrupted by server shutdown" || uploadExists(s, job.Upload) {
+		t.Errorf("Expected the running job to fail and its upload to be removed, got %+v", job)
+	}
+	if job, _ := s.jobs.get("a"); !uploadExists(s, job.Upload) {
+		t.Error("Expected the upload of the queued job to be kept")
+	}
+}
+
+func TestServerRunJob(t *testing.T) {
+	t.Run("Done", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+		opts := setupRun(t, server)
+		s := newTestServer(t, opts)
+		sample, err := os.ReadFile(opts.Input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := uploadName(t, s, "sample.wav", sample)
+		rec := serve(s, http.MethodPost, "/jobs", nil, map[string]string{"upload": name})
+		var job Job
+		json.Unmarshal(rec.Body.Bytes(), &job)
+		if rec := serve(s, http.MethodGet, "/jobs/"+job.ID+"/result", nil, nil); rec.Code != http.StatusConflict {
+			t.Errorf("Expected no result for a queued job, got %d: %s", rec.Code, rec.Body)
+		}
+
+		if err := s.runJob(context.Background(), <-s.queue); err != nil {
+			t.Fatalf("runJob failed: %v", err)
+		}
+
+		rec = serve(s, http.MethodGet, "/jobs/"+job.ID+"/result", nil, nil)
+		var result map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("Fetching result failed with status %d: %s", rec.Code, rec.Body)
+		}
+		if result["headline"] != "Enterprise Pricing Update" || result["summary"] == "" {
+			t.Errorf("Unexpected result %v", result)
+		}
+		if uploadExists(s, name) {
+			t.Error("Expected the upload of the finished job to be removed")
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+		server.Inject("/v1/chat/completions", fakeopenai.ServerError)
+		opts := setupRun(t, server)
+		s := newTestServer(t, opts)
+		sample, err := os.ReadFile(opts.Input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := uploadName(t, s, "sample.wav", sample)
+		serve(s, http.MethodPost, "/jobs", nil, map[string]string{"upload": name})
+		id := <-s.queue
+		if err := s.runJob(context.Background(), id); err == nil {
+			t.Fatal("Expected the job to fail")
+		}
+
+		rec := serve(s, http.MethodGet, "/jobs/"+id, nil, nil)
+		var job Job
+		json.Unmarshal(rec.Body.Bytes(), &job)
+		if job.Status != JobFailed || !strings.HasPrefix(job.Error, "edit: ") || job.Transcription == "" {
+			t.Errorf("Unexpected job %+v", job)
+		}
+		if uploadExists(s, name) {
+			t.Error("Expected the upload of the failed job to be removed")
+		}
+	})
+}
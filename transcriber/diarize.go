@@ -0,0 +1,78 @@
+package transcriber
+
+import (
+	"math"
+	"time"
+
+	// Packages
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/r4h4/article-helper/transcript"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// StereoDiarizer attributes segments of a stereo recording with one speaker
+// per channel, e.g. a two-person interview with a microphone each, to the
+// speaker whose channel is loudest during the segment
+type StereoDiarizer struct {
+	channels [2][]float32
+	names    map[string]string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewStereoDiarizer splits interleaved stereo samples into channels and
+// returns the diarizer along with the mono downmix for processing
+func NewStereoDiarizer(stereo []float32, names map[string]string) (*StereoDiarizer, []float32) {
+	n := len(stereo) / 2
+	d := &StereoDiarizer{names: names}
+	d.channels[0] = make([]float32, n)
+	d.channels[1] = make([]float32, n)
+
+	mono := make([]float32, n)
+	for i := 0; i < n; i++ {
+		l, r := stereo[2*i], stereo[2*i+1]
+		d.channels[0][i], d.channels[1][i] = l, r
+		mono[i] = (l + r) / 2
+	}
+	return d, mono
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Speaker returns the label, or name if one is mapped, of the speaker of the
+// segment. It returns an empty string on a nil diarizer.
+func (d *StereoDiarizer) Speaker(segment whisper.Segment) string {
+	if d == nil {
+		return ""
+	}
+
+	label := transcript.SpeakerLabel(1)
+	if rms(d.channels[1], segment.Start, segment.End) > rms(d.channels[0], segment.Start, segment.End) {
+		label = transcript.SpeakerLabel(2)
+	}
+	if name, ok := d.names[label]; ok && name != "" {
+		return name
+	}
+	return label
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func rms(samples []float32, start, end time.Duration) float64 {
+	from := min(len(samples), int(start.Seconds()*whisper.SampleRate))
+	to := min(len(samples), int(end.Seconds()*whisper.SampleRate))
+	if to <= from {
+		return 0
+	}
+
+	var sum float64
+	for _, s := range samples[from:to] {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(to-from))
+}
@@ -8,6 +8,8 @@ import (
 
 	// Packages
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/r4h4/article-helper/glossary"
+	"github.com/r4h4/article-helper/transcript"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -88,6 +90,22 @@ func (flags *Flags) GetWordThreshold() float32 {
 	return float32(flags.Lookup("word-thold").Value.(flag.Getter).Get().(float64))
 }
 
+func (flags *Flags) IsDiarize() bool {
+	return flags.Lookup("diarize").Value.String() == "true"
+}
+
+func (flags *Flags) GetSpeakerNames() (map[string]string, error) {
+	return transcript.ParseSpeakerNames(flags.Lookup("speakers").Value.String())
+}
+
+func (flags *Flags) GetPrompt() string {
+	return flags.Lookup("prompt").Value.String()
+}
+
+func (flags *Flags) GetGlossary() string {
+	return flags.Lookup("glossary").Value.String()
+}
+
 func (flags *Flags) SetParams(context whisper.Context) error {
 	if lang := flags.GetLanguage(); lang != "" && lang != "auto" {
 		fmt.Fprintf(flags.Output(), "Setting language to %q\n", lang)
@@ -123,6 +141,18 @@ func (flags *Flags) SetParams(context whisper.Context) error {
 		fmt.Fprintf(flags.Output(), "Setting word_threshold to %f\n", word_threshold)
 		context.SetTokenThreshold(word_threshold)
 	}
+	prompt := flags.GetPrompt()
+	if path := flags.GetGlossary(); path != "" {
+		g, err := glossary.Load(path)
+		if err != nil {
+			return err
+		}
+		prompt = strings.TrimSpace(prompt + " " + g.Prompt())
+	}
+	if prompt != "" {
+		fmt.Fprintf(flags.Output(), "Setting initial_prompt to %q\n", prompt)
+		context.SetInitialPrompt(prompt)
+	}
 
 	// Return success
 	return nil
@@ -144,4 +174,8 @@ func registerFlags(flag *Flags) {
 	flag.Bool("tokens", false, "Display tokens")
 	flag.Bool("colorize", false, "Colorize tokens")
 	flag.String("out", "", "Output format (srt, none or leave as empty string)")
+	flag.String("prompt", "", "Initial prompt")
+	flag.String("glossary", "", "Glossary file whose terms are added to the initial prompt")
+	flag.Bool("diarize", false, "Label speakers of a stereo recording with one speaker per channel")
+	flag.String("speakers", "", "Speaker names, e.g. SPEAKER_1=Alice,SPEAKER_2=Bob")
 }
@@ -3,7 +3,10 @@ package transcriber
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"runtime"
+	"strings"
 	"time"
 
 	// Package imports
@@ -33,7 +36,7 @@ func Process(modelName string, path string, flags *Flags) error {
 		return err
 	}
 
-	fmt.Printf("\n%s\n", context.SystemInfo())
+	slog.Debug("whisper.cpp system info", "info", context.SystemInfo())
 
 	// Open the file
 	fmt.Fprintf(flags.Output(), "Loading %q\n", path)
@@ -44,11 +47,21 @@ func Process(modelName string, path string, flags *Flags) error {
 	defer fh.Close()
 
 	// Decode the WAV file - load the full buffer
+	var speakers *StereoDiarizer
 	dec := wav.NewDecoder(fh)
 	if buf, err := dec.FullPCMBuffer(); err != nil {
 		return err
 	} else if dec.SampleRate != whisper.SampleRate {
 		return fmt.Errorf("unsupported sample rate: %d", dec.SampleRate)
+	} else if flags.IsDiarize() {
+		if dec.NumChans != 2 {
+			return fmt.Errorf("diarization requires a stereo recording with one speaker per channel, got %d channels", dec.NumChans)
+		}
+		names, err := flags.GetSpeakerNames()
+		if err != nil {
+			return err
+		}
+		speakers, data = NewStereoDiarizer(buf.AsFloat32Buffer().Data, names)
 	} else if dec.NumChans != 1 {
 		return fmt.Errorf("unsupported number of channels: %d", dec.NumChans)
 	} else {
@@ -81,19 +94,28 @@ func Process(modelName string, path string, flags *Flags) error {
 
 	context.PrintTimings()
 
+	// Report the language whisper.cpp detected
+	if flags.GetLanguage() == "auto" {
+		if lang, err := DetectLanguage(model, context, flags.GetThreads()); err != nil {
+			fmt.Fprintln(flags.Output(), "Language detection failed:", err)
+		} else {
+			fmt.Fprintf(flags.Output(), "Detected language: %s\n", lang)
+		}
+	}
+
 	// Print out the results
 	switch {
 	case flags.GetOut() == "srt":
-		return OutputSRT(os.Stdout, context)
+		return OutputSRT(os.Stdout, context, speakers)
 	case flags.GetOut() == "none":
 		return nil
 	default:
-		return Output(os.Stdout, context, flags.IsColorize())
+		return Output(os.Stdout, context, flags.IsColorize(), speakers)
 	}
 }
 
-// Output text as SRT file
-func OutputSRT(w io.Writer, context whisper.Context) error {
+// Output text as SRT file, prefixed with the speaker if speakers is not nil
+func OutputSRT(w io.Writer, context whisper.Context, speakers *StereoDiarizer) error {
 	n := 1
 	for {
 		segment, err := context.NextSegment()
@@ -104,14 +126,18 @@ func OutputSRT(w io.Writer, context whisper.Context) error {
 		}
 		fmt.Fprintln(w, n)
 		fmt.Fprintln(w, srtTimestamp(segment.Start), " --> ", srtTimestamp(segment.End))
-		fmt.Fprintln(w, segment.Text)
+		if speaker := speakers.Speaker(segment); speaker != "" {
+			fmt.Fprintf(w, "%s: %s\n", speaker, strings.TrimSpace(segment.Text))
+		} else {
+			fmt.Fprintln(w, segment.Text)
+		}
 		fmt.Fprintln(w, "")
 		n++
 	}
 }
 
-// Output text to terminal
-func Output(w io.Writer, context whisper.Context, colorize bool) error {
+// Output text to terminal, prefixed with the speaker if speakers is not nil
+func Output(w io.Writer, context whisper.Context, colorize bool, speakers *StereoDiarizer) error {
 	for {
 		segment, err := context.NextSegment()
 		if err == io.EOF {
@@ -120,6 +146,9 @@ func Output(w io.Writer, context whisper.Context, colorize bool) error {
 			return err
 		}
 		fmt.Fprintf(w, "[%6s->%6s]", segment.Start.Truncate(time.Millisecond), segment.End.Truncate(time.Millisecond))
+		if speaker := speakers.Speaker(segment); speaker != "" {
+			fmt.Fprintf(w, " %s:", speaker)
+		}
 		if colorize {
 			for _, token := range segment.Tokens {
 				if !context.IsText(token) {
@@ -138,3 +167,34 @@ func Output(w io.Writer, context whisper.Context, colorize bool) error {
 func srtTimestamp(t time.Duration) string {
 	return fmt.Sprintf("%02d:%02d:%02d,%03d", t/time.Hour, (t%time.Hour)/time.Minute, (t%time.Minute)/time.Second, (t%time.Second)/time.Millisecond)
 }
+
+// DetectLanguage returns the most probable spoken language of the processed audio
+func DetectLanguage(model whisper.Model, context whisper.Context, threads uint) (string, error) {
+	detector, ok := context.(interface {
+		WhisperLangAutoDetect(offset_ms int, n_threads int) ([]float32, error)
+	})
+	if !ok {
+		return "", fmt.Errorf("language detection is not supported")
+	}
+	if threads == 0 {
+		threads = uint(runtime.NumCPU())
+	}
+
+	probs, err := detector.WhisperLangAutoDetect(0, int(threads))
+	if err != nil {
+		return "", err
+	}
+
+	// Probabilities are indexed by language id, in the order of Languages()
+	languages := model.Languages()
+	best := -1
+	for i, p := range probs {
+		if i < len(languages) && (best < 0 || p > probs[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", fmt.Errorf("no language probabilities")
+	}
+	return languages[best], nil
+}
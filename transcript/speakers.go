@@ -0,0 +1,84 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Segment is a span of a transcript attributed to a single speaker
+type Segment struct {
+	Speaker string        `json:"speaker"`
+	Start   time.Duration `json:"start,omitempty"`
+	End     time.Duration `json:"end,omitempty"`
+	Text    string        `json:"text"`
+}
+
+// SpeakerLabel returns the generic label for the n-th speaker, starting at 1
+func SpeakerLabel(n int) string {
+	return fmt.Sprintf("SPEAKER_%d", n)
+}
+
+// Format renders segments as one "Speaker: text" line each, merging
+// consecutive segments of the same speaker
+func Format(segments []Segment) string {
+	var b strings.Builder
+	for i, s := range segments {
+		text := strings.TrimSpace(s.Text)
+		if i > 0 && segments[i-1].Speaker == s.Speaker {
+			b.WriteString(" " + text)
+			continue
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(s.Speaker + ": " + text)
+	}
+	return b.String()
+}
+
+// Rename replaces speaker labels with real names. Unmapped labels are kept.
+func Rename(segments []Segment, names map[string]string) []Segment {
+	renamed := make([]Segment, len(segments))
+	for i, s := range segments {
+		if name, ok := names[s.Speaker]; ok && name != "" {
+			s.Speaker = name
+		}
+		renamed[i] = s
+	}
+	return renamed
+}
+
+// ParseSpeakerNames parses a mapping such as "SPEAKER_1=Alice,SPEAKER_2=Bob"
+func ParseSpeakerNames(s string) (map[string]string, error) {
+	names := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return names, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		label, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid speaker mapping %q, expected LABEL=Name", pair)
+		}
+		names[strings.TrimSpace(label)] = strings.TrimSpace(name)
+	}
+	return names, nil
+}
+
+// LoadSpeakerNames reads a sidecar JSON file mapping speaker labels to names,
+// e.g. {"SPEAKER_1": "Alice", "SPEAKER_2": "Bob"}
+func LoadSpeakerNames(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading speaker names: %w", err)
+	}
+
+	var names map[string]string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("parsing speaker names %s: %w", path, err)
+	}
+	return names, nil
+}
@@ -0,0 +1,112 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []Segment
+		expected string
+	}{
+		{name: "Empty", segments: nil, expected: ""},
+		{
+			name:     "Single",
+			segments: []Segment{{Speaker: "SPEAKER_1", Text: " Hello. "}},
+			expected: "SPEAKER_1: Hello.",
+		},
+		{
+			name: "MergesConsecutiveSegments",
+			segments: []Segment{
+				{Speaker: "Alice", Text: "Hi Bob."},
+				{Speaker: "Alice", Text: "How are you?"},
+				{Speaker: "Bob", Text: "Fine."},
+				{Speaker: "Bob", Text: "Thanks."},
+				{Speaker: "Alice", Text: "Great."},
+			},
+			expected: "Alice: Hi Bob. How are you?\nBob: Fine. Thanks.\nAlice: Great.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.segments); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRename(t *testing.T) {
+	segments := []Segment{
+		{Speaker: SpeakerLabel(1), Text: "Hi."},
+		{Speaker: SpeakerLabel(2), Text: "Hello."},
+		{Speaker: SpeakerLabel(3), Text: "Hey."},
+	}
+	names := map[string]string{"SPEAKER_1": "Alice", "SPEAKER_2": "", "SPEAKER_9": "Carol"}
+
+	// Unmapped and empty names keep the label
+	expected := []string{"Alice", "SPEAKER_2", "SPEAKER_3"}
+	renamed := Rename(segments, names)
+	for i, s := range renamed {
+		if s.Speaker != expected[i] || s.Text != segments[i].Text {
+			t.Errorf("Expected segment %d by %s, got %+v", i, expected[i], s)
+		}
+	}
+	if segments[0].Speaker != "SPEAKER_1" {
+		t.Errorf("Expected the segments to be copied, got %+v", segments[0])
+	}
+}
+
+func TestParseSpeakerNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]string
+		err      string
+	}{
+		{input: "", expected: map[string]string{}},
+		{input: "  ", expected: map[string]string{}},
+		{input: "SPEAKER_1=Alice", expected: map[string]string{"SPEAKER_1": "Alice"}},
+		{input: " SPEAKER_1 = Alice , SPEAKER_2=Bob Smith", expected: map[string]string{"SPEAKER_1": "Alice", "SPEAKER_2": "Bob Smith"}},
+		{input: "SPEAKER_1=Alice,SPEAKER_2", err: `invalid speaker mapping "SPEAKER_2", expected LABEL=Name`},
+	}
+
+	for _, tt := range tests {
+		names, err := ParseSpeakerNames(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Expected error %q for %q, got %v", tt.err, tt.input, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(names, tt.expected) {
+			t.Errorf("Expected %v for %q, got %v (%v)", tt.expected, tt.input, names, err)
+		}
+	}
+}
+
+func TestLoadSpeakerNames(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "speakers.json")
+	if err := os.WriteFile(path, []byte(`{"SPEAKER_1": "Alice", "SPEAKER_2": "Bob"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := LoadSpeakerNames(path)
+	if err != nil || !reflect.DeepEqual(names, map[string]string{"SPEAKER_1": "Alice", "SPEAKER_2": "Bob"}) {
+		t.Errorf("Unexpected names %v (%v)", names, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`["Alice"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSpeakerNames(invalid); err == nil || !strings.HasPrefix(err.Error(), "parsing speaker names") {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package usage
+
+import (
+	"sort"
+	"sync"
+)
+
+// Price is the price of a model in US dollars
+type Price struct {
+	Input     float64 `json:"input,omitempty"`      // Per million prompt tokens
+	Output    float64 `json:"output,omitempty"`     // Per million completion tokens
+	PerMinute float64 `json:"per_minute,omitempty"` // Per minute of audio
+}
+
+// Prices maps model names to prices
+type Prices map[string]Price
+
+// DefaultPrices are the list prices of the models used by default
+var DefaultPrices = Prices{
+	"gpt-4o":                 {Input: 2.50, Output: 10.00},
+	"gpt-3.5-turbo":          {Input: 0.50, Output: 1.50},
+	"whisper-1":              {PerMinute: 0.006},
+	"text-embedding-3-small": {Input: 0.02},
+}
+
+// Cost returns the price of the given usage of model, zero for unknown models
+func (p Prices) Cost(model string, promptTokens, completionTokens int, audioSeconds float64) float64 {
+	price := p[model]
+	return (float64(promptTokens)*price.Input+float64(completionTokens)*price.Output)/1e6 +
+		audioSeconds/60*price.PerMinute
+}
+
+// Model sums the usage of one model
+type Model struct {
+	Model            string  `json:"model"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens,omitempty"`
+	CompletionTokens int     `json:"completion_tokens,omitempty"`
+	AudioSeconds     float64 `json:"audio_seconds,omitempty"`
+	Cost             float64 `json:"cost"`
+}
+
+// Add adds the usage of other to m
+func (m *Model) Add(other Model) {
+	m.Requests += other.Requests
+	m.PromptTokens += other.PromptTokens
+	m.CompletionTokens += other.CompletionTokens
+	m.AudioSeconds += other.AudioSeconds
+	m.Cost += other.Cost
+}
+
+// Summary is the usage of a run, per model
+type Summary struct {
+	Models []Model `json:"models"`
+	Cost   float64 `json:"cost"`
+}
+
+// Tracker records the API usage of a run. It is safe for concurrent use; a
+// nil Tracker records nothing.
+type Tracker struct {
+	prices Prices
+	mu     sync.Mutex
+	models map[string]*Model
+}
+
+// NewTracker returns a tracker pricing usage with prices, falling back to
+// DefaultPrices for models it does not list
+func NewTracker(prices Prices) *Tracker {
+	merged := make(Prices, len(DefaultPrices)+len(prices))
+	for model, p := range DefaultPrices {
+		merged[model] = p
+	}
+	for model, p := range prices {
+		merged[model] = p
+	}
+	return &Tracker{prices: merged, models: make(map[string]*Model)}
+}
+
+// AddTokens records a request that used tokens, e.g. a chat completion or embedding
+func (t *Tracker) AddTokens(model string, promptTokens, completionTokens int) {
+	t.add(Model{
+		Model:            model,
+		Requests:         1,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	})
+}
+
+// AddAudio records a transcription of the given duration
+func (t *Tracker) AddAudio(model string, seconds float64) {
+	t.add(Model{Model: model, Requests: 1, AudioSeconds: seconds})
+}
+
+func (t *Tracker) add(m Model) {
+	if t == nil {
+		return
+	}
+	m.Cost = t.prices.Cost(m.Model, m.PromptTokens, m.CompletionTokens, m.AudioSeconds)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.models[m.Model] == nil {
+		t.models[m.Model] = &Model{Model: m.Model}
+	}
+	t.models[m.Model].Add(m)
+}
+
+// Summary returns the usage recorded so far, sorted by model
+func (t *Tracker) Summary() *Summary {
+	s := &Summary{}
+	if t == nil {
+		return s
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, m := range t.models {
+		s.Models = append(s.Models, *m)
+		s.Cost += m.Cost
+	}
+	sort.Slice(s.Models, func(i, j int) bool { return s.Models[i].Model < s.Models[j].Model })
+	return s
+}
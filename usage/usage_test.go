@@ -0,0 +1,44 @@
+package usage
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(Prices{"gpt-4o": {Input: 5, Output: 15}})
+	tracker.AddTokens("gpt-4o", 1000, 500)
+	tracker.AddTokens("gpt-4o", 1000, 500)
+	tracker.AddAudio("whisper-1", 90)
+	tracker.AddTokens("unknown-model", 100, 100)
+
+	summary := tracker.Summary()
+	if len(summary.Models) != 3 {
+		t.Fatalf("Expected 3 models, got %+v", summary.Models)
+	}
+
+	gpt := summary.Models[0]
+	if gpt.Model != "gpt-4o" || gpt.Requests != 2 || gpt.PromptTokens != 2000 || gpt.CompletionTokens != 1000 {
+		t.Errorf("Unexpected gpt-4o usage: %+v", gpt)
+	}
+	// The configured price overrides the default
+	if expected := (2000*5.0 + 1000*15.0) / 1e6; math.Abs(gpt.Cost-expected) > 1e-9 {
+		t.Errorf("Expected gpt-4o cost %f, got %f", expected, gpt.Cost)
+	}
+
+	if whisper := summary.Models[2]; math.Abs(whisper.Cost-0.009) > 1e-9 {
+		t.Errorf("Expected whisper cost 0.009, got %f", whisper.Cost)
+	}
+	if unknown := summary.Models[1]; unknown.Cost != 0 {
+		t.Errorf("Expected unknown models to be free, got %f", unknown.Cost)
+	}
+	if math.Abs(summary.Cost-(gpt.Cost+0.009)) > 1e-9 {
+		t.Errorf("Expected the total to sum the models, got %f", summary.Cost)
+	}
+
+	var nilTracker *Tracker
+	nilTracker.AddAudio("whisper-1", 10)
+	if s := nilTracker.Summary(); len(s.Models) != 0 {
+		t.Errorf("Expected a nil tracker to record nothing, got %+v", s)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchIndexFile records the hashes of processed files in the recordings folder
+const watchIndexFile = ".watched.json"
+
+// watchedFile is an entry of the watch index
+type watchedFile struct {
+	Name      string    `json:"name"`
+	Folder    string    `json:"folder"`
+	Processed time.Time `json:"processed"`
+}
+
+// pendingFile is a file that may still be written to
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // When size and modification time last changed
+}
+
+type folderWatcher struct {
+	dir        string
+	archive    string
+	quarantine string
+	settle     time.Duration
+	opts       pipelineOptions
+
+	indexPath string
+	index     map[string]watchedFile // SHA-256 -> processed file
+	pending   map[string]*pendingFile
+}
+
+// runWatch processes audio files appearing in a folder, e.g. one synced from
+// a voice recorder. Processed files are moved to the archive, failed ones to
+// the quarantine along with an error log.
+func runWatch(ctx context.Context, opts pipelineOptions, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	archive := fs.String("archive", "", "Folder to move processed files to (default: <dir>/archive)")
+	quarantine := fs.String("quarantine", "", "Folder to move failed files to (default: <dir>/quarantine)")
+	settle := fs.Duration("settle", 5*time.Second, "How long a file must stay unchanged before it is processed")
+	poll := fs.Duration("poll", 30*time.Second, "Interval of full rescans, which catch events the file watcher missed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: watch [-archive dir] [-quarantine dir] [-settle duration] [-poll duration] <dir>")
+	}
+
+	w := &folderWatcher{
+		dir:        fs.Arg(0),
+		archive:    *archive,
+		quarantine: *quarantine,
+		settle:     *settle,
+		opts:       opts,
+		indexPath:  filepath.Join(recordingsDir, watchIndexFile),
+		pending:    make(map[string]*pendingFile),
+	}
+	if w.archive == "" {
+		w.archive = filepath.Join(w.dir, "archive")
+	}
+	if w.quarantine == "" {
+		w.quarantine = filepath.Join(w.dir, "quarantine")
+	}
+	for _, dir := range []string{w.archive, w.quarantine} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("creating folder: %w", err)
+		}
+	}
+	if err := w.loadIndex(); err != nil {
+		return err
+	}
+
+	// Fall back to polling where file events are not available, e.g. on network shares
+	var events chan fsnotify.Event
+	notifier, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = notifier.Add(w.dir)
+	}
+	if err != nil {
+		slog.Warn("File events unavailable, polling every 2s", "err", err)
+		*poll = 2 * time.Second
+	} else {
+		defer notifier.Close()
+		events = notifier.Events
+		go func() {
+			for err := range notifier.Errors {
+				slog.Error("Watch error", "err", err)
+			}
+		}()
+	}
+
+	if err := w.scan(); err != nil {
+		return err
+	}
+	slog.Info("Watching folder", "dir", w.dir)
+
+	check := time.NewTicker(time.Second)
+	defer check.Stop()
+	rescan := time.NewTicker(*poll)
+	defer rescan.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				w.track(event.Name)
+			}
+		case <-rescan.C:
+			if err := w.scan(); err != nil {
+				slog.Error("Scanning folder failed", "dir", w.dir, "err", err)
+			}
+		case <-check.C:
+			w.processStable(ctx)
+		}
+	}
+}
+
+// scan tracks all audio files in the folder
+func (w *folderWatcher) scan() error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return fmt.Errorf("reading watched folder: %w", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			w.track(filepath.Join(w.dir, e.Name()))
+		}
+	}
+	return nil
+}
+
+// track starts waiting for an audio file to become stable. Hidden files are
+// ignored, as sync tools use them for partial downloads.
+func (w *folderWatcher) track(path string) {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || !slices.Contains(uploadExtensions, strings.ToLower(filepath.Ext(name))) {
+		return
+	}
+	if _, ok := w.pending[path]; !ok {
+		w.pending[path] = &pendingFile{since: time.Now()}
+	}
+}
+
+// processStable processes the files that have not changed for the settle time
+func (w *folderWatcher) processStable(ctx context.Context) {
+	now := time.Now()
+	for path, p := range w.pending {
+		info, err := os.Stat(path)
+		if err != nil {
+			delete(w.pending, path)
+			continue
+		}
+		if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+			p.size, p.modTime, p.since = info.Size(), info.ModTime(), now
+			continue
+		}
+		if now.Sub(p.since) < w.settle || ctx.Err() != nil {
+			continue
+		}
+
+		delete(w.pending, path)
+		w.process(ctx, path)
+	}
+}
+
+func (w *folderWatcher) process(ctx context.Context, path string) {
+	name := filepath.Base(path)
+	hash, err := hashFile(path)
+	if err != nil {
+		slog.Error("Hashing file failed", "file", name, "err", err)
+		return
+	}
+
+	if seen, ok := w.index[hash]; ok {
+		slog.Info("Skipping file with known content", "file", name, "original", seen.Name, "folder", seen.Folder)
+		if err := moveFile(path, w.archive); err != nil {
+			slog.Error("Archiving file failed", "file", name, "err", err)
+		}
+		return
+	}
+
+	slog.Info("Processing file", "file", name)
+	opts := w.opts
+	opts.Input = path
+	opts.Quiet = true
+	state, pipeline, err := newRun(opts)
+	if err == nil {
+		err = runPipeline(ctx, pipeline, state, nil)
+	}
+
+	// Files interrupted by a shutdown are left in place to be processed again
+	if err != nil && ctx.Err() != nil {
+		slog.Info("Processing file interrupted", "file", name, "err", err)
+		return
+	}
+	if err != nil {
+		slog.Error("Processing file failed", "file", name, "err", err)
+		w.quarantineFile(path, err)
+		return
+	}
+
+	w.index[hash] = watchedFile{Name: name, Folder: state.OutFolder, Processed: time.Now()}
+	if err := w.saveIndex(); err != nil {
+		slog.Error("Saving watch index failed", "err", err)
+	}
+	if err := moveFile(path, w.archive); err != nil {
+		slog.Error("Archiving file failed", "file", name, "err", err)
+	}
+	slog.Info("Processed file", "file", name, "folder", state.OutFolder)
+}
+
+// quarantineFile moves a failed file aside and writes the error next to it
+func (w *folderWatcher) quarantineFile(path string, cause error) {
+	if err := moveFile(path, w.quarantine); err != nil {
+		slog.Error("Quarantining file failed", "file", filepath.Base(path), "err", err)
+		return
+	}
+
+	entry := fmt.Sprintf("%s %s: %v\n", time.Now().Format(time.RFC3339), filepath.Base(path), cause)
+	f, err := os.OpenFile(filepath.Join(w.quarantine, "errors.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		slog.Error("Writing error log failed", "err", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(entry); err != nil {
+		slog.Error("Writing error log failed", "err", err)
+	}
+}
+
+func (w *folderWatcher) loadIndex() error {
+	w.index = make(map[string]watchedFile)
+
+	data, err := os.ReadFile(w.indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("reading watch index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &w.index); err != nil {
+		return fmt.Errorf("parsing watch index %s: %w", w.indexPath, err)
+	}
+	return nil
+}
+
+func (w *folderWatcher) saveIndex() error {
+	data, err := json.MarshalIndent(w.index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding watch index: %w", err)
+	}
+	if err := os.WriteFile(w.indexPath+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("writing watch index: %w", err)
+	}
+	return os.Rename(w.indexPath+".tmp", w.indexPath)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// rename is replaced in tests to move files as if across devices
+var rename = os.Rename
+
+// moveFile moves a file into dir, numbering it if the name is taken. Files
+// are copied if dir is on another device.
+func moveFile(path, dir string) error {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	dst := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dst = filepath.Join(dir, fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), i, ext))
+	}
+
+	err := rename(path, dst)
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) || !errors.Is(linkErr.Err, syscall.EXDEV) {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
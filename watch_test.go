@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/r4h4/article-helper/internal/fakeopenai"
+)
+
+// newTestWatcher returns a watcher of dir with its archive and quarantine inside
+func newTestWatcher(t *testing.T, dir string, opts pipelineOptions) *folderWatcher {
+	t.Helper()
+
+	w := &folderWatcher{
+		dir:        dir,
+		archive:    filepath.Join(dir, "archive"),
+		quarantine: filepath.Join(dir, "quarantine"),
+		opts:       opts,
+		indexPath:  filepath.Join(t.TempDir(), watchIndexFile),
+		pending:    make(map[string]*pendingFile),
+	}
+	for _, d := range []string{w.archive, w.quarantine} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.loadIndex(); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestWatchProcessStable(t *testing.T) {
+	dir := t.TempDir()
+	w := newTestWatcher(t, dir, pipelineOptions{})
+	path := filepath.Join(dir, "memo.mp3")
+	if err := os.WriteFile(path, []byte("ID3 part"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hidden and unsupported files are ignored
+	w.track(filepath.Join(dir, ".memo.mp3.part"))
+	w.track(filepath.Join(dir, "notes.txt"))
+	w.track(path)
+	if len(w.pending) != 1 {
+		t.Fatalf("Expected only the audio file to be tracked, got %v", w.pending)
+	}
+
+	// Files known by their content are archived without processing them
+	if err := os.WriteFile(path, []byte("ID3 complete"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.index[hash] = watchedFile{Name: "earlier.mp3", Folder: "recordings/earlier"}
+
+	// The first check records the size, and the file must then stay unchanged for the settle time
+	w.settle = time.Hour
+	for range 2 {
+		w.processStable(context.Background())
+	}
+	if _, err := os.Stat(path); err != nil || len(w.pending) != 1 {
+		t.Fatalf("Expected the file to wait for the settle time, got %v with %d pending", err, len(w.pending))
+	}
+
+	w.settle = 0
+	w.processStable(context.Background())
+	if _, err := os.Stat(filepath.Join(w.archive, "memo.mp3")); err != nil || len(w.pending) != 0 {
+		t.Errorf("Expected the known file to be archived, got %v with %d pending", err, len(w.pending))
+	}
+}
+
+func TestWatchProcessChangingFile(t *testing.T) {
+	dir := t.TempDir()
+	w := newTestWatcher(t, dir, pipelineOptions{})
+	path := filepath.Join(dir, "memo.mp3")
+	if err := os.WriteFile(path, []byte("ID3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.track(path)
+	w.processStable(context.Background())
+
+	// A file still being written to restarts the settle time
+	if err := os.WriteFile(path, []byte("ID3 more"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	since := w.pending[path].since
+	time.Sleep(10 * time.Millisecond)
+	w.processStable(context.Background())
+	if p := w.pending[path]; p == nil || !p.since.After(since) || p.size != int64(len("ID3 more")) {
+		t.Errorf("Expected the change to be recorded, got %+v", p)
+	}
+
+	// Deleted files are no longer tracked
+	os.Remove(path)
+	w.processStable(context.Background())
+	if len(w.pending) != 0 {
+		t.Errorf("Expected the deleted file to be dropped, got %v", w.pending)
+	}
+}
+
+func TestWatchProcess(t *testing.T) {
+	t.Run("Failed", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+		server.Inject("/v1/chat/completions", fakeopenai.ServerError)
+		opts := setupRun(t, server)
+		w := newTestWatcher(t, filepath.Dir(opts.Input), opts)
+
+		w.process(context.Background(), opts.Input)
+
+		if _, err := os.Stat(filepath.Join(w.quarantine, "sample.wav")); err != nil {
+			t.Errorf("Expected the file to be quarantined: %v", err)
+		}
+		log, err := os.ReadFile(filepath.Join(w.quarantine, "errors.log"))
+		if err != nil || !strings.Contains(string(log), "sample.wav: edit: ") {
+			t.Errorf("Expected the error to be logged, got %q (%v)", log, err)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/cassettes/pipeline.json")
+		server.Inject("/v1/chat/completions", fakeopenai.Timeout(time.Minute))
+		opts := setupRun(t, server)
+		w := newTestWatcher(t, filepath.Dir(opts.Input), opts)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(200*time.Millisecond, cancel)
+
+		w.process(ctx, opts.Input)
+
+		if _, err := os.Stat(opts.Input); err != nil {
+			t.Errorf("Expected the file to be left in place: %v", err)
+		}
+		if entries, _ := os.ReadDir(w.quarantine); len(entries) != 0 {
+			t.Errorf("Expected nothing to be quarantined, got %v", entries)
+		}
+		if len(w.index) != 0 {
+			t.Errorf("Expected the file not to be indexed, got %v", w.index)
+		}
+	})
+}
+
+func TestMoveFile(t *testing.T) {
+	for _, crossDevice := range []bool{false, true} {
+		dir, dst := t.TempDir(), t.TempDir()
+		if crossDevice {
+			rename = func(oldpath, newpath string) error {
+				return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+			}
+			t.Cleanup(func() { rename = os.Rename })
+		}
+
+		// Taken names are numbered
+		for i, content := range []string{"first", "second"} {
+			path := filepath.Join(dir, "memo.mp3")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := moveFile(path, dst); err != nil {
+				t.Fatalf("moveFile failed (cross device %v): %v", crossDevice, err)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("Expected the source to be removed (cross device %v), got %v", crossDevice, err)
+			}
+
+			name := []string{"memo.mp3", "memo_1.mp3"}[i]
+			if data, err := os.ReadFile(filepath.Join(dst, name)); err != nil || string(data) != content {
+				t.Errorf("Expected %s to hold %q (cross device %v), got %q (%v)", name, content, crossDevice, data, err)
+			}
+		}
+	}
+}
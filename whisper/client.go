@@ -18,12 +18,17 @@ import (
 
 const (
 	maxFileSize = 25 * 1024 * 1024 // 25 MB, adjust as needed
+
+	// Model is the transcription model used for all requests
+	Model = "whisper-1"
 )
 
 // Config holds the configuration for the Whisper API client
 type Config struct {
 	APIEndpoint string
 	APIKey      string
+	Timeout     time.Duration     // Per request, default 30s
+	Transport   http.RoundTripper // Sends the requests, default http.DefaultTransport
 }
 
 // Client is a Whisper API client
@@ -38,30 +43,68 @@ func NewClient(config Config) *Client {
 		config.APIEndpoint = "https://api.openai.com/v1/audio/transcriptions"
 	}
 
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+
 	return &Client{
 		config: config,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   config.Timeout,
+			Transport: config.Transport,
 		},
 	}
 }
 
+// Options are optional parameters of a transcription request
+type Options struct {
+	// Language of the audio as ISO-639-1 code, e.g. "de". Detected when empty.
+	Language string
+	// Prompt with text preceding the audio or spellings of names and terms
+	Prompt string
+}
+
+// Transcription is the result of a transcription
+type Transcription struct {
+	Text     string    `json:"text"`
+	Language string    `json:"language"` // Detected or given language, e.g. "german"
+	Duration float64   `json:"duration"` // Duration of the audio in seconds
+	Segments []Segment `json:"segments"`
+}
+
+// Segment is a timed part of a transcription
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
 // TranscribeAudio transcribes an audio file using the Whisper API
 func (c *Client) TranscribeAudio(ctx context.Context, filePath string) (string, error) {
+	result, err := c.Transcribe(ctx, filePath, Options{})
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// Transcribe transcribes an audio file using the Whisper API, returning the
+// text along with the detected language and timed segments
+func (c *Client) Transcribe(ctx context.Context, filePath string, opts Options) (*Transcription, error) {
 	file, err := c.openAndValidateFile(filePath)
 	if err != nil {
-		return "", fmt.Errorf("file error: %w", err)
+		return nil, fmt.Errorf("file error: %w", err)
 	}
 	defer file.Close()
 
-	body, contentType, err := c.createMultipartRequest(file)
+	body, contentType, err := c.createMultipartRequest(file, opts)
 	if err != nil {
-		return "", fmt.Errorf("request creation error: %w", err)
+		return nil, fmt.Errorf("request creation error: %w", err)
 	}
 
-	var result string
+	var result *Transcription
 	operation := func() error {
-		resp, err := c.sendRequest(ctx, body, contentType)
+		resp, err := c.sendRequest(ctx, bytes.NewReader(body), contentType)
 		if err != nil {
 			return fmt.Errorf("API request error: %w", err)
 		}
@@ -73,7 +116,7 @@ func (c *Client) TranscribeAudio(ctx context.Context, filePath string) (string,
 
 	err = backoff.Retry(operation, backoff.WithContext(backoff.NewExponentialBackOff(), ctx))
 	if err != nil {
-		return "", fmt.Errorf("transcription failed after retries: %w", err)
+		return nil, fmt.Errorf("transcription failed after retries: %w", err)
 	}
 
 	return result, nil
@@ -106,7 +149,7 @@ func (c *Client) openAndValidateFile(filePath string) (*os.File, error) {
 
 	// Check file extension
 	ext := strings.ToLower(filepath.Ext(filePath))
-	allowedExtensions := []string{".mp3", ".mp4", ".mpeg", ".mpga", ".m4a", ".wav", ".webm"}
+	allowedExtensions := []string{".flac", ".mp3", ".mp4", ".mpeg", ".mpga", ".m4a", ".ogg", ".wav", ".webm"}
 	if !contains(allowedExtensions, ext) {
 		file.Close()
 		return nil, fmt.Errorf("unsupported file extension: %s", ext)
@@ -119,9 +162,9 @@ func (c *Client) openAndValidateFile(filePath string) (*os.File, error) {
 		file.Close()
 		return nil, fmt.Errorf("error reading file header: %w", err)
 	}
-	fileType := http.DetectContentType(buffer)
+	fileType := detectContentType(buffer)
 
-	allowedMimeTypes := []string{"audio/", "video/"}
+	allowedMimeTypes := []string{"audio/", "video/", "application/ogg"}
 	if !startsWithAny(fileType, allowedMimeTypes) {
 		file.Close()
 		return nil, fmt.Errorf("unsupported file type: %s", fileType)
@@ -137,6 +180,14 @@ func (c *Client) openAndValidateFile(filePath string) (*os.File, error) {
 	return file, nil
 }
 
+// Helper function to detect the content type, including formats http.DetectContentType does not know
+func detectContentType(header []byte) string {
+	if bytes.HasPrefix(header, []byte("fLaC")) {
+		return "audio/flac"
+	}
+	return http.DetectContentType(header)
+}
+
 // Helper function to check if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -157,7 +208,7 @@ func startsWithAny(s string, prefixes []string) bool {
 	return false
 }
 
-func (c *Client) createMultipartRequest(file *os.File) (*bytes.Buffer, string, error) {
+func (c *Client) createMultipartRequest(file *os.File, opts Options) ([]byte, string, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
@@ -171,20 +222,40 @@ func (c *Client) createMultipartRequest(file *os.File) (*bytes.Buffer, string, e
 		return nil, "", fmt.Errorf("error copying file to form: %w", err)
 	}
 
-	err = writer.WriteField("model", "whisper-1")
+	err = writer.WriteField("model", Model)
 	if err != nil {
 		return nil, "", fmt.Errorf("error writing model field: %w", err)
 	}
 
+	// verbose_json adds the detected language and segments to the text
+	err = writer.WriteField("response_format", "verbose_json")
+	if err != nil {
+		return nil, "", fmt.Errorf("error writing response format field: %w", err)
+	}
+
+	if opts.Language != "" {
+		err = writer.WriteField("language", opts.Language)
+		if err != nil {
+			return nil, "", fmt.Errorf("error writing language field: %w", err)
+		}
+	}
+
+	if opts.Prompt != "" {
+		err = writer.WriteField("prompt", opts.Prompt)
+		if err != nil {
+			return nil, "", fmt.Errorf("error writing prompt field: %w", err)
+		}
+	}
+
 	err = writer.Close()
 	if err != nil {
 		return nil, "", fmt.Errorf("error closing multipart writer: %w", err)
 	}
 
-	return body, writer.FormDataContentType(), nil
+	return body.Bytes(), writer.FormDataContentType(), nil
 }
 
-func (c *Client) sendRequest(ctx context.Context, body *bytes.Buffer, contentType string) (*http.Response, error) {
+func (c *Client) sendRequest(ctx context.Context, body io.Reader, contentType string) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, "POST", c.config.APIEndpoint, body)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -199,21 +270,25 @@ func (c *Client) sendRequest(ctx context.Context, body *bytes.Buffer, contentTyp
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status code: %d", resp.StatusCode)
+		resp.Body.Close()
+		err := fmt.Errorf("API request failed with status code: %d", resp.StatusCode)
+		// Retrying will not fix the request itself, e.g. an invalid key
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, backoff.Permanent(err)
+		}
+		return nil, err
 	}
 
 	return resp, nil
 }
 
-func (c *Client) parseResponse(resp *http.Response) (string, error) {
-	var result struct {
-		Text string `json:"text"`
-	}
+func (c *Client) parseResponse(resp *http.Response) (*Transcription, error) {
+	var result Transcription
 
 	err := json.NewDecoder(resp.Body).Decode(&result)
 	if err != nil {
-		return "", fmt.Errorf("error decoding response: %w", err)
+		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
 
-	return result.Text, nil
+	return &result, nil
 }
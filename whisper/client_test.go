@@ -0,0 +1,123 @@
+package whisper
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r4h4/article-helper/internal/fakeopenai"
+)
+
+// writeSilence writes a WAV file with a second of silence
+func writeSilence(t *testing.T) string {
+	t.Helper()
+
+	const rate, size = 16000, 16000 * 2
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+size))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []interface{}{uint32(16), uint16(1), uint16(1), uint32(rate), uint32(rate * 2), uint16(2), uint16(16)})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(size))
+	b.Write(make([]byte, size))
+
+	path := filepath.Join(t.TempDir(), "silence.wav")
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestClient(server *fakeopenai.Server) *Client {
+	return NewClient(Config{
+		APIEndpoint: server.BaseURL() + "/audio/transcriptions",
+		APIKey:      "test-api-key",
+		Timeout:     200 * time.Millisecond,
+	})
+}
+
+func TestTranscribe(t *testing.T) {
+	server := fakeopenai.New(t, "testdata/transcription.json")
+	client := newTestClient(server)
+	path := writeSilence(t)
+
+	result, err := client.Transcribe(context.Background(), path, Options{Language: "de", Prompt: "Glossary: Welt."})
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if result.Text != "Hallo Welt." || result.Language != "german" || result.Duration != 2.5 || len(result.Segments) != 1 {
+		t.Errorf("Unexpected transcription: %+v", result)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(requests))
+	}
+	for _, field := range []string{`name="model"`, Model, `name="language"`, "Glossary: Welt.", "verbose_json", "RIFF"} {
+		if !bytes.Contains(requests[0].Body, []byte(field)) {
+			t.Errorf("Expected %q in the request", field)
+		}
+	}
+}
+
+func TestTranscribeRetries(t *testing.T) {
+	server := fakeopenai.New(t, "testdata/transcription.json")
+	server.Inject("/v1/audio/transcriptions", fakeopenai.RateLimited, fakeopenai.ServerError, fakeopenai.Timeout(time.Second))
+	client := newTestClient(server)
+
+	result, err := client.Transcribe(context.Background(), writeSilence(t), Options{})
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if result.Text != "Hallo Welt." {
+		t.Errorf("Unexpected text %q", result.Text)
+	}
+	if n := len(server.Requests()); n != 4 {
+		t.Errorf("Expected 3 failed attempts and 1 successful one, got %d requests", n)
+	}
+}
+
+func TestTranscribeErrors(t *testing.T) {
+	t.Run("MalformedJSON", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/transcription.json")
+		for i := 0; i < 20; i++ {
+			server.Inject("/v1/audio/transcriptions", fakeopenai.MalformedJSON)
+		}
+		client := newTestClient(server)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := client.Transcribe(ctx, writeSilence(t), Options{}); err == nil {
+			t.Error("Expected malformed responses to fail")
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/transcription.json")
+		client := NewClient(Config{APIEndpoint: server.BaseURL() + "/audio/transcriptions"})
+
+		_, err := client.Transcribe(context.Background(), writeSilence(t), Options{})
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("Expected an unauthorized error, got %v", err)
+		}
+	})
+
+	t.Run("UnsupportedFile", func(t *testing.T) {
+		server := fakeopenai.New(t, "testdata/transcription.json")
+		path := filepath.Join(t.TempDir(), "notes.txt")
+		os.WriteFile(path, []byte("not audio"), 0644)
+
+		if _, err := newTestClient(server).Transcribe(context.Background(), path, Options{}); err == nil {
+			t.Error("Expected a text file to be rejected")
+		}
+		if n := len(server.Requests()); n != 0 {
+			t.Errorf("Expected no requests, got %d", n)
+		}
+	})
+}